@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// DenyList keeps the ids (jti) of access tokens revoked before their expiry.
+type DenyList interface {
+	Deny(jti string, expiresAt time.Time) error
+	IsDenied(jti string) (bool, error)
+}
+
+var denyList DenyList = NewMemoryDenyList()
+
+// SetDenyList replaces the deny-list consulted by TokenValid and ExtractTokenID.
+func SetDenyList(d DenyList) {
+	denyList = d
+}
+
+type memoryDenyList struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+// NewMemoryDenyList returns a process local DenyList. Entries are dropped once the token expires.
+func NewMemoryDenyList() DenyList {
+	return &memoryDenyList{entries: map[string]time.Time{}}
+}
+
+func (m *memoryDenyList) Deny(jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, exp := range m.entries {
+		if exp.Before(now) {
+			delete(m.entries, id)
+		}
+	}
+	m.entries[jti] = expiresAt
+	return nil
+}
+
+func (m *memoryDenyList) IsDenied(jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.entries[jti]
+	return ok, nil
+}
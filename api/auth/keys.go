@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+// SigningKey is a key identified by its kid. Private is nil for keys that can
+// only verify. For HMAC both Private and Public hold the shared secret.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+	// RetiredAt is set once the key stops signing. It keeps verifying tokens until ExpiresAt.
+	RetiredAt time.Time
+	ExpiresAt time.Time
+}
+
+// KeyManager holds the key currently used to sign tokens and every key still
+// accepted when verifying them.
+type KeyManager struct {
+	mu        sync.RWMutex
+	alg       jwt.SigningMethod
+	current   *SigningKey
+	keys      map[string]*SigningKey
+	retention time.Duration
+	stop      chan struct{}
+	// dir and interval are set with LoadKeyDir, seen lists the kids found by its previous scans
+	dir      string
+	interval time.Duration
+	seen     map[string]bool
+}
+
+// NewKeyManager returns an empty manager for alg keys.
+// Retired keys keep verifying tokens during retention, which should be at least the access token TTL.
+func NewKeyManager(alg string, retention time.Duration) (*KeyManager, error) {
+	method := jwt.GetSigningMethod(alg)
+	switch method {
+	case jwt.SigningMethodHS256, jwt.SigningMethodRS256, jwt.SigningMethodES256:
+	default:
+		return nil, fmt.Errorf("Unsupported signing algorithm: %s", alg)
+	}
+	return &KeyManager{alg: method, keys: map[string]*SigningKey{}, retention: retention}, nil
+}
+
+// NewKeyManagerFromConfig builds the manager from the JWT settings: the
+// secret for HS256, or the key directory, or the private key file and kid for
+// asymmetric keys. Without any a key is generated, which only suits a single instance.
+func NewKeyManagerFromConfig(cfg config.JWT) (*KeyManager, error) {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+	m, err := NewKeyManager(alg, AccessTokenTTL+time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	var key *SigningKey
+	switch {
+	case m.alg == jwt.SigningMethodHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("API_SECRET is required for HS256")
+		}
+		key = NewHMACKey(cfg.KeyID, []byte(cfg.Secret))
+	case cfg.KeyDir != "":
+		return m, m.LoadKeyDir(cfg.KeyDir, cfg.RotationInterval)
+	case cfg.PrivateKeyFile != "":
+		data, err := ioutil.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err = ParsePrivateKeyPEM(cfg.KeyID, alg, data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		slog.Warn("JWT_PRIVATE_KEY_FILE not set, generating an ephemeral key", "alg", alg)
+		key, err = GenerateKey(alg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.AddKey(key, true)
+	return m, nil
+}
+
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	if kid == "" {
+		kid = keyID(secret)
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, Private: secret, Public: secret}
+}
+
+// GenerateKey creates a random key for alg.
+func GenerateKey(alg string) (*SigningKey, error) {
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 32)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+		return NewHMACKey("", secret), nil
+	case jwt.SigningMethodRS256.Alg():
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey("", jwt.SigningMethodRS256, private, &private.PublicKey)
+	case jwt.SigningMethodES256.Alg():
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey("", jwt.SigningMethodES256, private, &private.PublicKey)
+	}
+	return nil, fmt.Errorf("Unsupported signing algorithm: %s", alg)
+}
+
+// ParsePrivateKeyPEM reads a PEM encoded RSA or EC private key for alg.
+func ParsePrivateKeyPEM(kid, alg string, data []byte) (*SigningKey, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(kid, jwt.SigningMethodRS256, private, &private.PublicKey)
+	case jwt.SigningMethodES256.Alg():
+		private, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(kid, jwt.SigningMethodES256, private, &private.PublicKey)
+	}
+	return nil, fmt.Errorf("Unsupported signing algorithm: %s", alg)
+}
+
+func newAsymmetricKey(kid string, method jwt.SigningMethod, private, public interface{}) (*SigningKey, error) {
+	if kid == "" {
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			return nil, err
+		}
+		kid = keyID(der)
+	}
+	return &SigningKey{ID: kid, Method: method, Private: private, Public: public}, nil
+}
+
+// keyID derives a stable kid so every instance sharing a key agrees on it
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:8])
+}
+
+// AddKey registers key for verification, and for signing when active.
+func (m *KeyManager) AddKey(key *SigningKey, active bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key.ID] = key
+	if active {
+		m.retire(m.current)
+		m.current = key
+	}
+}
+
+// LoadKeyDir uses the <kid>.pem private keys of dir, a directory shared by
+// every instance. Each key verifies tokens and the last kid in name order that
+// every instance already knows signs them: one seen by an earlier scan, or
+// whose file is older than interval. Keys are rotated by adding a file named
+// after the others, e.g. 2026-10-18.pem, which Rotate activates.
+func (m *KeyManager) LoadKeyDir(dir string, interval time.Duration) error {
+	m.mu.Lock()
+	m.dir, m.interval, m.seen = dir, interval, map[string]bool{}
+	m.mu.Unlock()
+	return m.Rotate()
+}
+
+// Rotate rescans the key directory and activates its next key. Keys removed
+// from it keep verifying tokens during the retention period.
+func (m *KeyManager) Rotate() error {
+	m.mu.RLock()
+	dir, interval := m.dir, m.interval
+	m.mu.RUnlock()
+	if dir == "" {
+		return errors.New("No key directory to rotate from")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	found := map[string]*SigningKey{}
+	var active, newest *SigningKey
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".pem" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		kid := strings.TrimSuffix(file.Name(), ".pem")
+		key, err := ParsePrivateKeyPEM(kid, m.alg.Alg(), data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file.Name(), err)
+		}
+		found[kid] = key
+
+		// ReadDir sorts by name, the last key wins
+		newest = key
+		m.mu.RLock()
+		known := m.seen[kid]
+		m.mu.RUnlock()
+		if known || now.Sub(file.ModTime()) >= interval {
+			active = key
+		}
+	}
+	if newest == nil {
+		return fmt.Errorf("No .pem key in %s", dir)
+	}
+	// A new directory only has unknown keys, every instance starts with the same one
+	if active == nil {
+		active = newest
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for kid, key := range found {
+		m.seen[kid] = true
+		if existing, ok := m.keys[kid]; !ok || !existing.ExpiresAt.IsZero() {
+			m.keys[kid] = key
+		}
+	}
+	for kid, key := range m.keys {
+		if _, ok := found[kid]; !ok && key != active {
+			m.retire(key)
+		}
+	}
+	m.current = m.keys[active.ID]
+	m.retire(nil)
+	return nil
+}
+
+// StartRotation rescans the key directory every interval until Stop is called.
+func (m *KeyManager) StartRotation(interval time.Duration) {
+	m.mu.Lock()
+	if m.stop != nil || interval <= 0 {
+		m.mu.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := m.Rotate()
+				if err != nil {
+					slog.Error("cannot rotate signing key", "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (m *KeyManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+func (m *KeyManager) retire(key *SigningKey) {
+	now := time.Now()
+	if key != nil && key.RetiredAt.IsZero() {
+		key.RetiredAt = now
+		key.ExpiresAt = now.Add(m.retention)
+	}
+	for id, k := range m.keys {
+		if !k.ExpiresAt.IsZero() && k.ExpiresAt.Before(now) {
+			delete(m.keys, id)
+		}
+	}
+}
+
+// Sign signs claims with the current key and sets the kid header.
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+	if key == nil || key.Private == nil {
+		return "", errors.New("No signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key of a token from its kid. Tokens
+// without kid, issued before key management existed, use the current key.
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := m.current
+	if kid, ok := token.Header["kid"].(string); ok {
+		key = m.keys[kid]
+	}
+	if key == nil || (!key.ExpiresAt.IsZero() && key.ExpiresAt.Before(time.Now())) {
+		return nil, fmt.Errorf("Unknown signing key: %v", token.Header["kid"])
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+var (
+	keysMu  sync.Mutex
+	manager *KeyManager
+)
+
+// SetKeyManager replaces the manager used by CreateToken and the token parsers.
+func SetKeyManager(m *KeyManager) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	manager = m
+}
+
+var ErrNoKeyManager = errors.New("no signing keys configured")
+
+// Keys returns the key manager set with SetKeyManager.
+func Keys() (*KeyManager, error) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if manager == nil {
+		return nil, ErrNoKeyManager
+	}
+	return manager, nil
+}
@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+func writeKey(t *testing.T, dir, kid string, modified time.Time) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, kid+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func signedKid(t *testing.T, m *auth.KeyManager) string {
+	t.Helper()
+	token, err := m.Sign(jwt.MapClaims{"user_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, m.Keyfunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header["kid"].(string)
+}
+
+func TestKeyDirRotation(t *testing.T) {
+	dir := t.TempDir()
+	interval := time.Hour
+	writeKey(t, dir, "2026-01", time.Now().Add(-2*interval))
+	cfg := config.JWT{Algorithm: "ES256", KeyDir: dir, RotationInterval: interval}
+
+	first, err := auth.NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, signedKid(t, first), "2026-01")
+
+	// A new key is published first and signs from the next scan on
+	writeKey(t, dir, "2026-02", time.Now())
+	second, err := auth.NewKeyManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, signedKid(t, second), "2026-01")
+	if err = first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, signedKid(t, first), "2026-01")
+	assert.Equal(t, len(first.JWKS().Keys), 2)
+
+	if err = first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token, err := first.Sign(jwt.MapClaims{"user_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every instance verifies the tokens of the others
+	parsed, err := jwt.Parse(token, second.Keyfunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, parsed.Header["kid"], "2026-02")
+
+	// Removed keys keep verifying until the retention period ends
+	if err = os.Remove(filepath.Join(dir, "2026-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err = second.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, signedKid(t, second), "2026-02")
+	assert.Equal(t, len(second.JWKS().Keys), 2)
+}
+
+func TestKeyDirWithoutKeys(t *testing.T) {
+	_, err := auth.NewKeyManagerFromConfig(config.JWT{Algorithm: "RS256", KeyDir: t.TempDir()})
+	assert.NotEqual(t, err, nil)
+}
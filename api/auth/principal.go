@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Principal is the authenticated caller of a request, taken from its access token.
+type Principal struct {
+	UserID    uint32
+	Roles     []string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ParsePrincipal verifies the access token of the request and returns its principal.
+func ParsePrincipal(r *http.Request) (*Principal, error) {
+	claims, err := parseClaims(r)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["user_id"]), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{UserID: uint32(uid)}
+	principal.TokenID, _ = claims["jti"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, name)
+			}
+		}
+	}
+	return principal, nil
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by the authentication middleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserIDFromContext returns the id of the authenticated user, or 0 and false for anonymous requests.
+func UserIDFromContext(ctx context.Context) (uint32, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return p.UserID, true
+}
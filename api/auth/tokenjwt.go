@@ -1,87 +1,109 @@
 package auth
 
 import (
-	"encoding/json"
-	"fmt"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"net/http"
-	"os"
-	"time"
 	"strings"
-	"log"
-	"strconv"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-func CreateToken(user_id uint32) (string, error){
-	claims :=jwt.MapClaims{}
-	claims["authorized"]=true
-	claims["user_id"]=user_id
-	claims["exp"]=time.Now().Add(time.Hour*1).Unix() //Token expires after 1 hour
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
-}
+var AccessTokenTTL = time.Hour * 1
 
-func TokenValid(r *http.Request) error{
-	tokenString:=ExtractToken(r)
-	token, err:=jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error){
-		if _, ok:=token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
-	if err !=nil{
-		return err
+var ErrTokenRevoked = errors.New("Token has been revoked")
+
+// CreateToken issues an access token for the user carrying its roles
+func CreateToken(user_id uint32, roles ...string) (string, error) {
+	jti, err := NewTokenID()
+	if err != nil {
+		return "", err
 	}
-	if claims, ok:=token.Claims.(jwt.MapClaims); ok && token.Valid{
-		Pretty(claims)
+	claims := jwt.MapClaims{}
+	claims["authorized"] = true
+	claims["user_id"] = user_id
+	claims["roles"] = append([]string{}, roles...)
+	claims["jti"] = jti
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(AccessTokenTTL).Unix() //Token expires after AccessTokenTTL
+	keys, err := Keys()
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return keys.Sign(claims)
 }
 
-func ExtractToken(r *http.Request) string{
-	keys:=r.URL.Query()
-	token:=keys.Get("token")
-	if token!=""{
+func TokenValid(r *http.Request) error {
+	_, err := parseClaims(r)
+	return err
+}
+
+func ExtractToken(r *http.Request) string {
+	keys := r.URL.Query()
+	token := keys.Get("token")
+	if token != "" {
 		return token
 	}
-	bearerToken:=r.Header.Get("Authorization")
-	if len(strings.Split(bearerToken, " ")) == 2{
+	bearerToken := r.Header.Get("Authorization")
+	if len(strings.Split(bearerToken, " ")) == 2 {
 		return strings.Split(bearerToken, " ")[1]
 	}
 	return ""
 }
 
-func ExtractTokenID(r *http.Request) (uint32, error){
-	tokenString:=ExtractToken(r)
-	token, err:=jwt.Parse(tokenString, func(token *jwt.Token)(interface{}, error){
-		if _,ok:=token.Method.(*jwt.SigningMethodHMAC); !ok{
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
-
-	if err!=nil{
+func ExtractTokenID(r *http.Request) (uint32, error) {
+	principal, err := ParsePrincipal(r)
+	if err != nil {
 		return 0, err
 	}
+	return principal.UserID, nil
+}
+
+// Revoke puts the access token of the principal on the deny-list until it expires
+func Revoke(p *Principal) error {
+	if p.TokenID == "" {
+		return errors.New("Token has no id")
+	}
+	return denyList.Deny(p.TokenID, p.ExpiresAt)
+}
+
+// parseClaims verifies the request token and makes sure it was not revoked
+func parseClaims(r *http.Request) (jwt.MapClaims, error) {
+	keys, err := Keys()
+	if err != nil {
+		return nil, err
+	}
+	tokenString := ExtractToken(r)
+	token, err := jwt.Parse(tokenString, keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
 
-	claims, ok:=token.Claims.(jwt.MapClaims)
-	if ok && token.Valid{
-		uid, err:=strconv.ParseUint(fmt.Sprintf("%.0f", claims["user_id"]), 10, 32)
-		if err!=nil{
-			return 0, err
+	if jti, ok := claims["jti"].(string); ok {
+		denied, err := denyList.IsDenied(jti)
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			return nil, ErrTokenRevoked
 		}
-		return uint32(uid), nil
 	}
-	return 0, nil
+	return claims, nil
 }
 
-// Pretty display the claims licely in the terminal
-func Pretty(data interface{}){
-	b, err:=json.MarshalIndent(data, "", " ")
-	if err!=nil{
-		log.Println(err)
-		return
+// NewTokenID returns a random identifier, used for jti claims and refresh token families
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
 	}
-	fmt.Println(string(b))
-}
\ No newline at end of file
+	return hex.EncodeToString(b), nil
+}
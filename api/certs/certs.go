@@ -0,0 +1,172 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader serves the certificate and client CAs read from disk, reloading
+// them when one of the files changes.
+type Reloader struct {
+	cfg config.TLS
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewReloader loads the files of cfg, failing if any of them is invalid.
+func NewReloader(cfg config.TLS) (*Reloader, error) {
+	r := &Reloader{cfg: cfg, stop: make(chan struct{})}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) load() error {
+	modTimes := map[string]time.Time{}
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("%s: no PEM certificate found", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// Files being replaced may be missing for a moment
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch polls the files every interval until Stop. A failed reload keeps
+// serving the previous certificate.
+func (r *Reloader) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !r.changed() {
+					continue
+				}
+				if err := r.load(); err != nil {
+					slog.Error("TLS reload failed, keeping the current certificate", "error", err)
+					continue
+				}
+				slog.Info("TLS certificate reloaded", "cert_file", r.cfg.CertFile)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *Reloader) Stop() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ServerConfig returns the TLS configuration of the API. Client certificates
+// are verified against the client CAs current at each handshake.
+func (r *Reloader) ServerConfig() (*tls.Config, error) {
+	minVersion, ok := minVersions[r.cfg.MinVersion]
+	if !ok {
+		return nil, errors.New("unsupported TLS minimum version " + r.cfg.MinVersion)
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"http/1.1"},
+	}
+	if r.cfg.HTTP2 {
+		base.NextProtos = []string{"h2", "http/1.1"}
+	}
+	if r.cfg.ClientCAFile == "" {
+		return base, nil
+	}
+
+	clientAuth := tls.RequireAndVerifyClientCert
+	if r.cfg.ClientAuth == "verify_if_given" {
+		clientAuth = tls.VerifyClientCertIfGiven
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.ClientAuth = clientAuth
+		c.ClientCAs = r.clientCA
+		return c, nil
+	}
+	return base, nil
+}
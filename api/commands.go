@@ -0,0 +1,131 @@
+package api
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/database"
+	"github.com/antonio91capa/go-apirest/api/dummy"
+	"github.com/antonio91capa/go-apirest/api/logging"
+	"github.com/antonio91capa/go-apirest/api/migrations"
+	"github.com/jinzhu/gorm"
+)
+
+func openDB(cfg *config.Config) (*gorm.DB, error) {
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return nil, failure("cannot open the database", "error", err)
+	}
+	logging.UseWithGorm(db, slog.Default())
+	return db, nil
+}
+
+// ****** migrate up|down|status
+func migrate(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return usageError(usage)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator := migrations.New(db)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return failure("migration failed", "error", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		m, err := migrator.Down()
+		if err != nil {
+			return failure("rollback failed", "error", err)
+		}
+		fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return failure("cannot read migration status", "error", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		return usageError(fmt.Sprintf("unknown migrate command %q\n%s", args[0], usage))
+	}
+	return nil
+}
+
+// ****** seed [-users N] [-posts N] [fixture files...]
+func seed(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	fakeUsers := flags.Int("users", 0, "number of fake users to generate")
+	fakePosts := flags.Int("posts", 0, "number of fake posts to generate, spread over the fake users")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go-apirest seed [-users N] [-posts N] [fixture.yaml|fixture.json ...]")
+		fmt.Fprintln(flags.Output(), "Without files nor fake data the sample users and posts are loaded.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	fixtures := &dummy.Fixtures{}
+	for _, path := range flags.Args() {
+		f, err := dummy.LoadFile(path)
+		if err != nil {
+			return failure("cannot load fixtures", "error", err)
+		}
+		fixtures.Merge(f)
+	}
+	if *fakeUsers > 0 || *fakePosts > 0 {
+		f, err := dummy.Fake(*fakeUsers, *fakePosts)
+		if err != nil {
+			return failure("cannot generate fake data", "error", err)
+		}
+		fixtures.Merge(f)
+	}
+	if flags.NArg() == 0 && *fakeUsers == 0 && *fakePosts == 0 {
+		fixtures = &dummy.Sample
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	pending, err := migrations.New(db).Pending()
+	if err != nil {
+		return failure("cannot read migration status", "error", err)
+	}
+	if len(pending) > 0 {
+		return failure("the schema is not up to date, run \"migrate up\" first")
+	}
+
+	result, err := fixtures.Apply(db)
+	if err != nil {
+		return failure("seeding failed", "error", err)
+	}
+	fmt.Printf("users: %d created, %d updated\n", result.UsersCreated, result.UsersUpdated)
+	fmt.Printf("posts: %d created, %d updated\n", result.PostsCreated, result.PostsUpdated)
+	return nil
+}
@@ -0,0 +1,531 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the whole API configuration. Each setting is read, from lowest to
+// highest precedence, from its default, the config file, the environment
+// (including an optional .env file) and the command line flags.
+type Config struct {
+	HTTP     HTTP     `yaml:"http"`
+	Database Database `yaml:"database"`
+	JWT      JWT      `yaml:"jwt"`
+	Log      Log      `yaml:"log"`
+	Tracing  Tracing  `yaml:"tracing"`
+	API      API      `yaml:"api"`
+}
+
+// API configures the versions of the API. The routes are served under /v1,
+// the unversioned paths of before the versioning are aliases of v1.
+type API struct {
+	// Aliases serves the v1 routes without their prefix too, default true. Env API_ALIASES.
+	Aliases bool `yaml:"aliases"`
+	// AliasDeprecation marks the aliases deprecated since then, announced in
+	// the Deprecation header. Env API_ALIAS_DEPRECATION.
+	AliasDeprecation time.Time `yaml:"alias_deprecation"`
+	// AliasSunset is when the aliases will stop being served, announced in
+	// the Sunset header. Env API_ALIAS_SUNSET.
+	AliasSunset time.Time `yaml:"alias_sunset"`
+}
+
+type Log struct {
+	// Level is debug, info (default), warn or error. SQL queries are logged at debug.
+	Level string `yaml:"level"`
+	// Format is json (default) or text.
+	Format string `yaml:"format"`
+}
+
+type Tracing struct {
+	// Exporter is none (default), stdout or otlp. Env OTEL_TRACES_EXPORTER.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector, host:port or URL, default "localhost:4318".
+	Endpoint string `yaml:"endpoint"`
+	// Insecure sends the OTLP spans over plain HTTP.
+	Insecure bool `yaml:"insecure"`
+	// ServiceName identifies the API in the traces, default "go-apirest".
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio is the share of new traces recorded, between 0 and 1. Incoming
+	// requests follow the sampling decision of their parent.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type HTTP struct {
+	// Addr is the listen address. Env API_ADDR, flag -addr, default ":8080".
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on SIGINT/SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	TLS             TLS           `yaml:"tls"`
+}
+
+// TLS is enabled when both CertFile and KeyFile are set.
+type TLS struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// MinVersion is "1.2" (default) or "1.3".
+	MinVersion string `yaml:"min_version"`
+	// ClientCAFile enables mutual TLS, client certificates are verified against it.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is "require" (default) or "verify_if_given" with a client CA.
+	ClientAuth string `yaml:"client_auth"`
+	// ReloadInterval is how often the files are checked for changes, 0 disables reloading.
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// HTTP2 is negotiated over TLS unless disabled, default true.
+	HTTP2 bool `yaml:"http2"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type Database struct {
+	// Driver is mysql, postgres or sqlite. Env DB_DRIVER, default "mysql".
+	Driver string `yaml:"driver"`
+	// Host defaults to "127.0.0.1", env DB_HOST.
+	Host string `yaml:"host"`
+	// Port defaults to 3306 for mysql and 5432 for postgres, env DB_PORT.
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// Name is the database name, or the file path (or :memory:) with sqlite.
+	Name string `yaml:"name"`
+	// AutoMigrate applies pending migrations when serving, needed by
+	// :memory: databases. Env DB_AUTO_MIGRATE, default false.
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// Connection pool limits, 0 means unlimited. SQLite always uses a single connection.
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+type JWT struct {
+	// Algorithm is HS256 (default), RS256 or ES256. Env JWT_ALG.
+	Algorithm string `yaml:"algorithm"`
+	// Secret signs HS256 tokens and is required for it. Env API_SECRET.
+	Secret string `yaml:"secret"`
+	// PrivateKeyFile is a PEM key for RS256/ES256, one is generated when empty.
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// KeyID is the kid of the key, derived from the key when empty.
+	KeyID string `yaml:"key_id"`
+	// KeyDir holds the RS256/ES256 keys shared by every instance as <kid>.pem
+	// files, the last kid in name order signing. Env JWT_KEY_DIR.
+	KeyDir string `yaml:"key_dir"`
+	// RotationInterval rescans KeyDir periodically to activate the next key, 0 disables it.
+	RotationInterval time.Duration `yaml:"rotation_interval"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			TLS: TLS{
+				MinVersion:     "1.2",
+				ClientAuth:     "require",
+				ReloadInterval: 30 * time.Second,
+				HTTP2:          true,
+			},
+		},
+		Database: Database{
+			Driver:          "mysql",
+			Host:            "127.0.0.1",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		JWT: JWT{
+			Algorithm: "HS256",
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			ServiceName: "go-apirest",
+			SampleRatio: 1,
+		},
+		API: API{
+			Aliases: true,
+		},
+	}
+}
+
+var defaultPorts = map[string]string{
+	"mysql":    "3306",
+	"postgres": "5432",
+}
+
+// setting binds a field to its environment variable and flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	get   func() string
+	set   func(string) error
+}
+
+func stringSetting(env, name, usage string, p *string) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get:   func() string { return *p },
+		set:   func(v string) error { *p = v; return nil },
+	}
+}
+
+func durationSetting(env, name, usage string, p *time.Duration) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get:   func() string { return p.String() },
+		set: func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			*p = d
+			return nil
+		},
+	}
+}
+
+func intSetting(env, name, usage string, p *int) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get:   func() string { return strconv.Itoa(*p) },
+		set: func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			*p = i
+			return nil
+		},
+	}
+}
+
+func floatSetting(env, name, usage string, p *float64) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get:   func() string { return strconv.FormatFloat(*p, 'g', -1, 64) },
+		set: func(v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			*p = f
+			return nil
+		},
+	}
+}
+
+func boolSetting(env, name, usage string, p *bool) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get:   func() string { return strconv.FormatBool(*p) },
+		set: func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			*p = b
+			return nil
+		},
+	}
+}
+
+// timeSetting accepts RFC 3339 timestamps or plain dates, empty is the zero time.
+func timeSetting(env, name, usage string, p *time.Time) setting {
+	return setting{
+		env:   env,
+		flag:  name,
+		usage: usage,
+		get: func() string {
+			if p.IsZero() {
+				return ""
+			}
+			return p.Format(time.RFC3339)
+		},
+		set: func(v string) error {
+			if v == "" {
+				*p = time.Time{}
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				t, err = time.Parse("2006-01-02", v)
+			}
+			if err != nil {
+				return fmt.Errorf("%q is not an RFC 3339 timestamp or a date", v)
+			}
+			*p = t
+			return nil
+		},
+	}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		stringSetting("API_ADDR", "addr", "HTTP listen address", &c.HTTP.Addr),
+		durationSetting("HTTP_READ_TIMEOUT", "http-read-timeout", "maximum duration for reading a whole request", &c.HTTP.ReadTimeout),
+		durationSetting("HTTP_READ_HEADER_TIMEOUT", "http-read-header-timeout", "maximum duration for reading request headers", &c.HTTP.ReadHeaderTimeout),
+		durationSetting("HTTP_WRITE_TIMEOUT", "http-write-timeout", "maximum duration for writing a response", &c.HTTP.WriteTimeout),
+		durationSetting("HTTP_IDLE_TIMEOUT", "http-idle-timeout", "how long idle keep-alive connections are kept", &c.HTTP.IdleTimeout),
+		durationSetting("HTTP_SHUTDOWN_TIMEOUT", "http-shutdown-timeout", "how long in-flight requests are drained on shutdown", &c.HTTP.ShutdownTimeout),
+		stringSetting("DB_DRIVER", "db-driver", "database driver: mysql, postgres or sqlite", &c.Database.Driver),
+		stringSetting("DB_HOST", "db-host", "database host", &c.Database.Host),
+		stringSetting("DB_PORT", "db-port", "database port, the driver's default when empty", &c.Database.Port),
+		stringSetting("DB_USER", "db-user", "database user", &c.Database.User),
+		stringSetting("DB_PASSWORD", "db-password", "database password", &c.Database.Password),
+		stringSetting("DB_NAME", "db-name", "database name, or file path (or :memory:) with sqlite", &c.Database.Name),
+		stringSetting("TLS_CERT_FILE", "tls-cert-file", "PEM certificate, enables TLS with -tls-key-file", &c.HTTP.TLS.CertFile),
+		stringSetting("TLS_KEY_FILE", "tls-key-file", "PEM private key of the certificate", &c.HTTP.TLS.KeyFile),
+		stringSetting("TLS_MIN_VERSION", "tls-min-version", "minimum TLS version: 1.2 or 1.3", &c.HTTP.TLS.MinVersion),
+		stringSetting("TLS_CLIENT_CA_FILE", "tls-client-ca-file", "PEM CA bundle verifying client certificates (mutual TLS)", &c.HTTP.TLS.ClientCAFile),
+		stringSetting("TLS_CLIENT_AUTH", "tls-client-auth", "client certificates with a client CA: require or verify_if_given", &c.HTTP.TLS.ClientAuth),
+		durationSetting("TLS_RELOAD_INTERVAL", "tls-reload-interval", "how often certificate files are checked for changes, 0 disables it", &c.HTTP.TLS.ReloadInterval),
+		boolSetting("HTTP2", "http2", "negotiate HTTP/2 over TLS", &c.HTTP.TLS.HTTP2),
+		intSetting("DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections, 0 is unlimited", &c.Database.MaxOpenConns),
+		intSetting("DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle database connections", &c.Database.MaxIdleConns),
+		durationSetting("DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection, 0 is unlimited", &c.Database.ConnMaxLifetime),
+		boolSetting("DB_AUTO_MIGRATE", "db-auto-migrate", "apply pending migrations when serving", &c.Database.AutoMigrate),
+		stringSetting("JWT_ALG", "jwt-alg", "token signing algorithm: HS256, RS256 or ES256", &c.JWT.Algorithm),
+		stringSetting("API_SECRET", "jwt-secret", "secret signing HS256 tokens", &c.JWT.Secret),
+		stringSetting("JWT_PRIVATE_KEY_FILE", "jwt-private-key-file", "PEM private key for RS256/ES256, generated if empty", &c.JWT.PrivateKeyFile),
+		stringSetting("JWT_KEY_ID", "jwt-key-id", "kid of the signing key, derived from the key if empty", &c.JWT.KeyID),
+		stringSetting("JWT_KEY_DIR", "jwt-key-dir", "directory of <kid>.pem keys shared by every instance, for RS256/ES256", &c.JWT.KeyDir),
+		durationSetting("JWT_ROTATION_INTERVAL", "jwt-rotation-interval", "how often JWT_KEY_DIR is rescanned to activate the next key, 0 disables it", &c.JWT.RotationInterval),
+		stringSetting("LOG_LEVEL", "log-level", "log level: debug, info, warn or error", &c.Log.Level),
+		stringSetting("LOG_FORMAT", "log-format", "log format: json or text", &c.Log.Format),
+		stringSetting("OTEL_TRACES_EXPORTER", "trace-exporter", "trace exporter: none, stdout or otlp", &c.Tracing.Exporter),
+		stringSetting("OTEL_EXPORTER_OTLP_ENDPOINT", "trace-endpoint", "OTLP/HTTP collector, host:port or URL", &c.Tracing.Endpoint),
+		boolSetting("OTEL_EXPORTER_OTLP_INSECURE", "trace-insecure", "send OTLP spans over plain HTTP", &c.Tracing.Insecure),
+		stringSetting("OTEL_SERVICE_NAME", "trace-service-name", "service name in the traces", &c.Tracing.ServiceName),
+		floatSetting("OTEL_TRACES_SAMPLER_ARG", "trace-sample-ratio", "share of new traces recorded, between 0 and 1", &c.Tracing.SampleRatio),
+		boolSetting("API_ALIASES", "api-aliases", "serve the v1 routes without the /v1 prefix too", &c.API.Aliases),
+		timeSetting("API_ALIAS_DEPRECATION", "api-alias-deprecation", "date the unversioned aliases are deprecated since, announced in the Deprecation header", &c.API.AliasDeprecation),
+		timeSetting("API_ALIAS_SUNSET", "api-alias-sunset", "date the unversioned aliases stop being served, announced in the Sunset header", &c.API.AliasSunset),
+	}
+}
+
+// Load reads the configuration from the sources described on Config and
+// returns it with the arguments left after the flags. usage is printed
+// before the flag defaults on -h.
+func Load(args []string, usage string) (*Config, []string, error) {
+	c := Default()
+	settings := c.settings()
+
+	flags := flag.NewFlagSet("go-apirest", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML or JSON config file (env CONFIG_FILE)")
+	envFile := flags.String("env-file", ".env", "dotenv file loaded into the environment, ignored when missing")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.flag] = flags.String(s.flag, "", fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env, s.get()))
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintln(flags.Output(), "\noptions:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	// Variables already in the environment win over the .env file
+	if err := godotenv.Load(*envFile); err != nil && (given["env-file"] || !os.IsNotExist(err)) {
+		return nil, nil, fmt.Errorf("env file %s: %v", *envFile, err)
+	}
+
+	path := *configFile
+	if !given["config"] {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(v); err != nil {
+				return nil, nil, fmt.Errorf("%s: %v", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if given[s.flag] {
+			if err := s.set(*values[s.flag]); err != nil {
+				return nil, nil, fmt.Errorf("-%s: %v", s.flag, err)
+			}
+		}
+	}
+
+	if c.Database.Port == "" {
+		c.Database.Port = defaultPorts[c.Database.Driver]
+	}
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return c, flags.Args(), nil
+}
+
+// readFile merges a YAML or JSON file into c, JSON being valid YAML.
+func (c *Config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %v", err)
+	}
+	if err = yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	problems := []string{}
+
+	if c.HTTP.Addr == "" {
+		problems = append(problems, "API_ADDR must not be empty")
+	}
+	durations := []struct {
+		env   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTP.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"TLS_RELOAD_INTERVAL", c.HTTP.TLS.ReloadInterval},
+		{"DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime},
+		{"JWT_ROTATION_INTERVAL", c.JWT.RotationInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			problems = append(problems, d.env+" must not be negative")
+		}
+	}
+	if tls := c.HTTP.TLS; tls.Enabled() {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		if tls.MinVersion != "1.2" && tls.MinVersion != "1.3" {
+			problems = append(problems, fmt.Sprintf("TLS_MIN_VERSION %q is not supported, use 1.2 or 1.3", tls.MinVersion))
+		}
+		if tls.ClientAuth != "require" && tls.ClientAuth != "verify_if_given" {
+			problems = append(problems, fmt.Sprintf("TLS_CLIENT_AUTH %q is not supported, use require or verify_if_given", tls.ClientAuth))
+		}
+	} else if c.HTTP.TLS.ClientCAFile != "" {
+		problems = append(problems, "TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	}
+
+	switch c.Database.Driver {
+	case "mysql", "postgres":
+		if c.Database.Host == "" {
+			problems = append(problems, "DB_HOST must not be empty")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "DB_NAME must not be empty")
+		}
+	case "sqlite", "sqlite3":
+		if c.Database.Name == "" {
+			problems = append(problems, "DB_NAME must be a file path or :memory: with sqlite")
+		}
+		if c.Database.Name == ":memory:" && !c.Database.AutoMigrate {
+			problems = append(problems, "DB_AUTO_MIGRATE must be true with an in-memory sqlite database")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER %q is not supported, use mysql, postgres or sqlite", c.Database.Driver))
+	}
+
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if c.JWT.Secret == "" {
+			problems = append(problems, "API_SECRET must not be empty with HS256")
+		}
+		if c.JWT.KeyDir != "" || c.JWT.RotationInterval > 0 {
+			problems = append(problems, "JWT_KEY_DIR and JWT_ROTATION_INTERVAL need RS256 or ES256")
+		}
+	case "RS256", "ES256":
+		if c.JWT.KeyDir != "" && c.JWT.PrivateKeyFile != "" {
+			problems = append(problems, "JWT_KEY_DIR and JWT_PRIVATE_KEY_FILE must not be set together")
+		}
+		if c.JWT.RotationInterval > 0 && c.JWT.KeyDir == "" {
+			problems = append(problems, "JWT_ROTATION_INTERVAL needs JWT_KEY_DIR, the keys every instance rotates to")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("JWT_ALG %q is not supported, use HS256, RS256 or ES256", c.JWT.Algorithm))
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q is not supported, use debug, info, warn or error", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT %q is not supported, use json or text", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			problems = append(problems, "OTEL_EXPORTER_OTLP_ENDPOINT must not be empty with the otlp exporter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("OTEL_TRACES_EXPORTER %q is not supported, use none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "OTEL_TRACES_SAMPLER_ARG must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		problems = append(problems, "OTEL_SERVICE_NAME must not be empty")
+	}
+
+	if api := c.API; !api.AliasDeprecation.IsZero() && !api.AliasSunset.IsZero() && !api.AliasSunset.After(api.AliasDeprecation) {
+		problems = append(problems, "API_ALIAS_SUNSET must be after API_ALIAS_DEPRECATION")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+// unsetenv clears key for the test, a .env file may then set it. The previous
+// value is restored afterwards.
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	cases := []struct {
+		name string
+		// file is the config file named config<ext>, none when empty
+		file, ext string
+		dotenv    string
+		env       map[string]string
+		args      []string
+
+		addr        string
+		readTimeout time.Duration
+		err         string
+	}{
+		{name: "defaults", addr: ":8080", readTimeout: 15 * time.Second},
+		{
+			name: "yaml file", ext: ".yaml",
+			file: "http:\n  addr: \":9000\"\n  read_timeout: 20s\n",
+			addr: ":9000", readTimeout: 20 * time.Second,
+		},
+		{
+			name: "json file", ext: ".json",
+			file: `{"http": {"addr": ":9000", "read_timeout": "1m30s"}}`,
+			addr: ":9000", readTimeout: 90 * time.Second,
+		},
+		{
+			name: ".env over file", ext: ".yaml",
+			file:   "http:\n  addr: \":9000\"\n  read_timeout: 20s\n",
+			dotenv: "API_ADDR=:9100\n",
+			addr:   ":9100", readTimeout: 20 * time.Second,
+		},
+		{
+			name:   "env over .env",
+			dotenv: "API_ADDR=:9100\nHTTP_READ_TIMEOUT=25s\n",
+			env:    map[string]string{"API_ADDR": ":9200"},
+			addr:   ":9200", readTimeout: 25 * time.Second,
+		},
+		{
+			name: "flags over env", ext: ".yaml",
+			file: "http:\n  addr: \":9000\"\n",
+			env:  map[string]string{"API_ADDR": ":9200", "HTTP_READ_TIMEOUT": "25s"},
+			args: []string{"-addr", ":9300", "-http-read-timeout", "2m"},
+			addr: ":9300", readTimeout: 2 * time.Minute,
+		},
+		{
+			name: "invalid duration in env",
+			env:  map[string]string{"HTTP_READ_TIMEOUT": "soon"},
+			err:  "HTTP_READ_TIMEOUT",
+		},
+		{
+			name: "invalid duration flag",
+			args: []string{"-http-read-timeout", "10"},
+			err:  "-http-read-timeout",
+		},
+		{
+			name: "invalid file", ext: ".yaml",
+			file: "http: [",
+			err:  "config file",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, key := range []string{"CONFIG_FILE", "API_ADDR", "HTTP_READ_TIMEOUT"} {
+				unsetenv(t, key)
+			}
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+
+			dir := t.TempDir()
+			envFile := filepath.Join(dir, ".env")
+			if err := os.WriteFile(envFile, []byte(c.dotenv), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"-env-file", envFile, "-jwt-secret", "secret", "-db-name", "app"}
+			if c.file != "" {
+				path := filepath.Join(dir, "config"+c.ext)
+				if err := os.WriteFile(path, []byte(c.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-config", path)
+			}
+
+			cfg, _, err := config.Load(append(args, c.args...), "")
+			if c.err != "" {
+				assert.NotEqual(t, err, nil)
+				assert.Equal(t, strings.Contains(err.Error(), c.err), true)
+				return
+			}
+			assert.Equal(t, err, nil)
+			assert.Equal(t, cfg.HTTP.Addr, c.addr)
+			assert.Equal(t, cfg.HTTP.ReadTimeout, c.readTimeout)
+			// The other settings keep their defaults
+			assert.Equal(t, cfg.HTTP.WriteTimeout, 30*time.Second)
+			assert.Equal(t, cfg.Database.Port, "3306")
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := config.Default()
+	c.JWT.Secret = "secret"
+	c.Database.Name = "app"
+	assert.Equal(t, c.Validate(), nil)
+
+	c.HTTP.Addr = ""
+	c.HTTP.ReadTimeout = -time.Second
+	c.Database.Driver = "oracle"
+	c.JWT.Secret = ""
+	c.Log.Level = "loud"
+	err := c.Validate()
+	assert.NotEqual(t, err, nil)
+	for _, problem := range []string{
+		"API_ADDR must not be empty",
+		"HTTP_READ_TIMEOUT must not be negative",
+		`DB_DRIVER "oracle" is not supported`,
+		"API_SECRET must not be empty with HS256",
+		`LOG_LEVEL "loud" is not supported`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("%q does not report %q", err, problem)
+		}
+	}
+	assert.Equal(t, strings.Count(err.Error(), "; "), 4)
+}
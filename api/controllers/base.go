@@ -1,60 +1,210 @@
 package controllers
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 
-	_ "github.com/jinzhu/gorm/dialects/mysql"
-	_ "github.com/jinzhu/gorm/dialects/postgres"
-
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/certs"
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/database"
+	"github.com/antonio91capa/go-apirest/api/logging"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
 	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+	"github.com/antonio91capa/go-apirest/api/services"
 )
 
 type Server struct {
 	DB     *gorm.DB
 	Router *mux.Router
+	// Users, Posts, RefreshTokens and Transactor default to the gorm
+	// implementations on DB
+	Users         repository.UserRepository
+	Posts         repository.PostRepository
+	RefreshTokens repository.RefreshTokenRepository
+	Transactor    repository.Transactor
+	// Metrics is the Prometheus registry exposed on /metrics
+	Metrics *prometheus.Registry
+	// Logger receives the access log and the SQL statements, slog.Default when nil
+	Logger *slog.Logger
+	// API sets the unversioned aliases of the v1 routes
+	API config.API
+
+	httpMetrics *middlewares.Metrics
+	users       *services.UserService
+	posts       *services.PostService
+	tokens      *services.TokenService
+	versions    map[string]*mux.Router
 }
 
-func (server *Server) Initialize(DbDriver, DbUser, DbPassword, DbPort, DbHost, DbName string) {
-	var err error
+func (server *Server) Initialize(cfg config.Database) error {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	server.logger().Info("connected to the database", "driver", cfg.Driver)
 
-	if DbDriver == "mysql" {
-		DbURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
-		//DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
-		
-		server.DB, err = gorm.Open(DbDriver, DbURL)
-		if err != nil {
-			fmt.Printf("Cannot connect to %s database", DbDriver)
-			log.Fatal("This is the error: ", err)
-		} else {
-			fmt.Printf("We are connected to the %s database", DbDriver)
-		}
+	server.Setup(db)
+	return nil
+}
+
+// Setup wires the server around an open database: the repositories left
+// unset, the services, the token deny-list, the metrics and the routes. db may
+// be nil when every repository is set, revoked access tokens are then only
+// kept in memory.
+func (server *Server) Setup(db *gorm.DB) {
+	server.DB = db
+	if server.Users == nil {
+		server.Users = repository.NewGormUserRepository(db)
+	}
+	if server.Posts == nil {
+		server.Posts = repository.NewGormPostRepository(db)
+	}
+	if server.RefreshTokens == nil {
+		server.RefreshTokens = repository.NewGormRefreshTokenRepository(db)
+	}
+	if server.Transactor == nil && db != nil {
+		server.Transactor = repository.NewGormTransactor(db)
 	}
+	server.users = services.NewUserService(server.Users, server.Posts, server.Transactor)
+	server.posts = services.NewPostService(server.Posts, server.Users, server.Transactor)
+	server.tokens = services.NewTokenService(server.RefreshTokens, server.Users, server.Transactor)
 
-	if DbDriver == "postgres" {
-		DbURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
-		server.DB, err = gorm.Open(DbDriver, DbURL)
+	server.Metrics = prometheus.NewRegistry()
+	server.Metrics.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	server.httpMetrics = middlewares.NewMetrics(server.Metrics)
+
+	if db != nil {
+		logging.UseWithGorm(server.DB, server.logger())
+		// Revoked access tokens are shared through the database
+		auth.SetDenyList(models.NewTokenDenyList(server.DB))
+		database.RegisterMetrics(server.DB, server.Metrics)
+		database.RegisterTracing(server.DB)
+	} else {
+		auth.SetDenyList(auth.NewMemoryDenyList())
+	}
+
+	server.Router = mux.NewRouter()
+	server.versions = map[string]*mux.Router{}
+
+	server.initializeRoutes()
+}
+
+// APIVersion returns the router of the routes under /version, such as v1 or
+// v2, creating it on first use. Handlers of a new version are registered on
+// it next to the ones of the previous versions.
+func (server *Server) APIVersion(version string) *mux.Router {
+	if router, ok := server.versions[version]; ok {
+		return router
+	}
+	router := server.Router.PathPrefix("/" + version).Subrouter()
+	server.versions[version] = router
+	return router
+}
+
+// Handler is the router wrapped in the middlewares applying to every request.
+func (server *Server) Handler() http.Handler {
+	var handler http.Handler = server.Router
+	if server.httpMetrics != nil {
+		handler = server.httpMetrics.Handler(server.Router)
+	}
+	return middlewares.Tracing(server.Router, middlewares.AccessLog(server.logger(), handler))
+}
+
+func (server *Server) logger() *slog.Logger {
+	if server.Logger == nil {
+		return slog.Default()
+	}
+	return server.Logger
+}
+
+// Run serves the API until SIGINT or SIGTERM, then stops accepting
+// connections, drains in-flight requests and closes the database.
+func (server *Server) Run(cfg config.HTTP) error {
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           server.Handler(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	// Bound before logging, a taken address fails here and not after "listening"
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		server.Close()
+		return err
+	}
+
+	errs := make(chan error, 1)
+	if cfg.TLS.Enabled() {
+		reloader, err := certs.NewReloader(cfg.TLS)
 		if err != nil {
-			fmt.Printf("Cannot connect to %s database", DbDriver)
-			log.Fatal("This is the error: ", err)
-		} else {
-			fmt.Printf("We are connected to the %s database", DbDriver)
+			listener.Close()
+			server.Close()
+			return err
+		}
+		if srv.TLSConfig, err = reloader.ServerConfig(); err != nil {
+			listener.Close()
+			server.Close()
+			return err
+		}
+		if !cfg.TLS.HTTP2 {
+			srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
 		}
+		if cfg.TLS.ReloadInterval > 0 {
+			reloader.Watch(cfg.TLS.ReloadInterval)
+			defer reloader.Stop()
+		}
+		server.logger().Info("listening", "addr", listener.Addr().String(), "tls", true)
+		go func() {
+			errs <- srv.ServeTLS(listener, "", "")
+		}()
+	} else {
+		server.logger().Info("listening", "addr", listener.Addr().String(), "tls", false)
+		go func() {
+			errs <- srv.Serve(listener)
+		}()
 	}
 
-	//Database migration
-	server.DB.Debug().AutoMigrate(&models.User{}, &models.Post{})
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
 
-	server.Router = mux.NewRouter()
+	select {
+	case err := <-errs:
+		server.Close()
+		return err
+	case sig := <-stop:
+		server.logger().Info("shutting down", "signal", sig.String())
+	}
 
-	server.initializeRoutes()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	if closeErr := server.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-func (server *Server) Run(addr string) {
-	fmt.Println("Listening to port 8080")
-	log.Fatal(http.ListenAndServe(addr, server.Router))
+// Close releases the database connections.
+func (server *Server) Close() error {
+	if server.DB == nil {
+		return nil
+	}
+	return server.DB.Close()
 }
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+
+	"github.com/antonio91capa/go-apirest/api/docs"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+// docsPolicy only lets Swagger UI load the embedded assets and the document,
+// its components set inline styles
+const docsPolicy = "default-src 'none'; script-src 'self'; style-src 'self' 'unsafe-inline'; connect-src 'self'; img-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// OpenAPI serves the OpenAPI 3 document of the routes.
+func (server *Server) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs.OpenAPI(server.API.Aliases))
+}
+
+// Docs serves Swagger UI for the OpenAPI document.
+func (server *Server) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs.UI())
+}
+
+// DocsAsset serves the scripts and stylesheets of Swagger UI.
+func (server *Server) DocsAsset(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["asset"]
+	asset, ok := docs.Asset(name)
+	if !ok {
+		responses.Error(w, r, http.StatusNotFound, nil)
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(asset)
+}
@@ -0,0 +1,97 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+// openAPI is the part of the OpenAPI document the tests look at
+type openAPI struct {
+	OpenAPI string                            `json:"openapi"`
+	Servers []openAPIServer                   `json:"servers"`
+	Paths   map[string]map[string]interface{} `json:"paths"`
+}
+
+func (ts *testServer) openAPI() openAPI {
+	ts.t.Helper()
+	rec := ts.do("GET", "/openapi.json", "", nil)
+	assert.Equal(ts.t, rec.Code, http.StatusOK)
+	assert.Equal(ts.t, rec.Header().Get("Content-Type"), "application/json")
+	doc := openAPI{}
+	decode(ts.t, rec, &doc)
+	return doc
+}
+
+// operations returns the "METHOD /path" of every operation of the document,
+// once for each server of its path.
+func (doc openAPI) operations(t *testing.T) map[string]bool {
+	t.Helper()
+	operations := map[string]bool{}
+	for path, item := range doc.Paths {
+		servers := doc.Servers
+		if raw, ok := item["servers"]; ok {
+			b, _ := json.Marshal(raw)
+			servers = nil
+			if err := json.Unmarshal(b, &servers); err != nil {
+				t.Fatalf("servers of %s: %v", path, err)
+			}
+		}
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				for _, server := range servers {
+					operations[strings.ToUpper(method)+" "+strings.TrimSuffix(server.URL, "/")+path] = true
+				}
+			}
+		}
+	}
+	return operations
+}
+
+// TestOpenAPICoversRoutes fails when a route is missing from openapi.json, or
+// when the document describes an operation the router does not serve.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	for _, aliases := range []bool{true, false} {
+		t.Run(fmt.Sprintf("aliases=%t", aliases), func(t *testing.T) {
+			ts := newTestServerWith(t, config.API{Aliases: aliases})
+			doc := ts.openAPI()
+			assert.MatchRegex(t, doc.OpenAPI, `^3\.`)
+
+			operations := doc.operations(t)
+			served := routes(t, ts.server.Router)
+			for _, route := range sorted(served) {
+				if !operations[route] {
+					t.Errorf("route %s is missing from api/docs/openapi.json", route)
+				}
+			}
+			for _, operation := range sorted(operations) {
+				if !served[operation] {
+					t.Errorf("operation %s of api/docs/openapi.json is not served", operation)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIServers(t *testing.T) {
+	urls := func(doc openAPI) []string {
+		urls := []string{}
+		for _, server := range doc.Servers {
+			urls = append(urls, server.URL)
+		}
+		return urls
+	}
+	assert.Equal(t, urls(newTestServerWith(t, config.API{Aliases: true}).openAPI()), []string{"/v1", "/"})
+	assert.Equal(t, urls(newTestServer(t).openAPI()), []string{"/v1"})
+}
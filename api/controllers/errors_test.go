@@ -0,0 +1,71 @@
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/controllers"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+var errDriver = errors.New(`pq: relation "users" does not exist`)
+
+// brokenUsers fails every lookup like a broken database would
+type brokenUsers struct {
+	repository.UserRepository
+}
+
+func (brokenUsers) FindByID(ctx context.Context, id uint32) (*models.User, error) {
+	return nil, errDriver
+}
+
+type brokenPosts struct {
+	repository.PostRepository
+}
+
+func (brokenPosts) FindAll(ctx context.Context, filter models.PostFilter, page models.Pagination) ([]models.Post, *models.PageInfo, error) {
+	return nil, nil, errDriver
+}
+
+func (brokenPosts) FindByID(ctx context.Context, id uint64) (*models.Post, error) {
+	return nil, errDriver
+}
+
+// TestInternalErrorsAreHidden checks errors outside of the exception package
+// are answered with a generic 500, whatever status the handler asked for.
+func TestInternalErrorsAreHidden(t *testing.T) {
+	memory := repository.NewMemory()
+	server := &controllers.Server{
+		Users:      brokenUsers{memory.Users()},
+		Posts:      brokenPosts{memory.Posts()},
+		Transactor: memory,
+		Logger:     slog.Default(),
+	}
+	server.Setup(nil)
+	ts := &testServer{t: t, server: server, handler: server.Handler()}
+
+	requests := []struct{ method, path string }{
+		{"GET", "/v1/users/1"},
+		{"GET", "/v1/posts"},
+		{"DELETE", "/v1/posts/1"},
+	}
+	token, err := auth.CreateToken(1, models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range requests {
+		rec := ts.do(req.method, req.path, token, nil)
+		assert.Equal(t, rec.Code, http.StatusInternalServerError)
+		p := problem(t, rec)
+		assert.Equal(t, p.Code, "internal")
+		assert.Equal(t, strings.Contains(rec.Body.String(), "pq:"), false)
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// parsePostFilter reads the author_id, q, created_after, created_before and sort query parameters.
+func parsePostFilter(r *http.Request) (models.PostFilter, error) {
+	var err error
+	filter := models.PostFilter{}
+	query := r.URL.Query()
+
+	if authorID := query.Get("author_id"); authorID != "" {
+		id, err := strconv.ParseUint(authorID, 10, 32)
+		if err != nil || id == 0 {
+			return filter, exception.InvalidParameter("author_id", "integer", "Invalid author_id")
+		}
+		filter.AuthorID = uint32(id)
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("q"))
+
+	if after := query.Get("created_after"); after != "" {
+		filter.CreatedAfter, err = parseTime(after)
+		if err != nil {
+			return filter, exception.InvalidParameter("created_after", "datetime", "Invalid created_after")
+		}
+	}
+	if before := query.Get("created_before"); before != "" {
+		filter.CreatedBefore, err = parseTime(before)
+		if err != nil {
+			return filter, exception.InvalidParameter("created_before", "datetime", "Invalid created_before")
+		}
+	}
+
+	filter.Sort, err = models.ParseSort(query.Get("sort"), models.PostSortFields)
+	if err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates.
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parsePostExpansion reads the comma separated include query parameter.
+func parsePostExpansion(r *http.Request) (models.PostExpansion, error) {
+	expansion := models.PostExpansion{}
+	include := r.URL.Query().Get("include")
+	if include == "" {
+		return expansion, nil
+	}
+	for _, name := range strings.Split(include, ",") {
+		switch strings.TrimSpace(name) {
+		case "author":
+			expansion.Author = true
+		default:
+			return expansion, exception.InvalidParameter("include", "oneof", "Invalid include")
+		}
+	}
+	return expansion, nil
+}
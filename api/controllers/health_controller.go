@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/migrations"
+	"github.com/antonio91capa/go-apirest/api/responses"
+	"github.com/antonio91capa/go-apirest/api/version"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// Healthz tells the process is alive, it never touches dependencies.
+func (server *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	responses.ResponseJSON(w, http.StatusOK, responses.Health{Status: responses.StatusUp})
+}
+
+// Readyz tells whether the API can serve traffic: the database answers and
+// its schema is fully migrated.
+func (server *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	health := responses.Health{
+		Status: responses.StatusUp,
+		Checks: map[string]responses.Check{
+			"database":   server.checkDatabase(ctx),
+			"migrations": server.checkMigrations(ctx),
+		},
+	}
+	status := http.StatusOK
+	for _, check := range health.Checks {
+		if check.Status != responses.StatusUp {
+			health.Status = responses.StatusDown
+			status = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	responses.ResponseJSON(w, status, health)
+}
+
+func (server *Server) checkDatabase(ctx context.Context) responses.Check {
+	if server.DB == nil {
+		return responses.Check{Status: responses.StatusDown, Error: "no database connection"}
+	}
+	if err := server.DB.DB().PingContext(ctx); err != nil {
+		return server.checkFailed(ctx, "database", "database unreachable", err)
+	}
+	return responses.Check{Status: responses.StatusUp}
+}
+
+type migrationDetails struct {
+	Current int64   `json:"current"`
+	Pending []int64 `json:"pending"`
+}
+
+func (server *Server) checkMigrations(ctx context.Context) responses.Check {
+	if server.DB == nil {
+		return responses.Check{Status: responses.StatusDown, Error: "no database connection"}
+	}
+	status, err := migrations.New(server.DB).ReadStatus(ctx)
+	if err != nil {
+		return server.checkFailed(ctx, "migrations", "migration status unavailable", err)
+	}
+
+	details := migrationDetails{Pending: []int64{}}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			details.Pending = append(details.Pending, s.Version)
+		} else if s.Version > details.Current {
+			details.Current = s.Version
+		}
+	}
+	check := responses.Check{Status: responses.StatusUp, Details: details}
+	if len(details.Pending) > 0 {
+		check.Status = responses.StatusDown
+		check.Error = "pending migrations"
+	}
+	return check
+}
+
+// checkFailed logs why the check name failed and reports it down with a fixed
+// message, the probe is unauthenticated and must not leak the error.
+func (server *Server) checkFailed(ctx context.Context, name, message string, err error) responses.Check {
+	server.logger().ErrorContext(ctx, "readiness check failed", "check", name, "error", err)
+	return responses.Check{Status: responses.StatusDown, Error: message}
+}
+
+// Version reports the build of the running binary.
+func (server *Server) Version(w http.ResponseWriter, r *http.Request) {
+	responses.ResponseJSON(w, http.StatusOK, version.Get())
+}
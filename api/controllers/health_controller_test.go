@@ -0,0 +1,56 @@
+package controllers_test
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/controllers"
+	"github.com/antonio91capa/go-apirest/api/database"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+func TestReadyzBeforeMigrations(t *testing.T) {
+	db, err := database.Open(config.Database{Driver: "sqlite", Name: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &controllers.Server{Logger: slog.Default()}
+	server.Setup(db)
+	t.Cleanup(func() { server.Close() })
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, rec.Code, http.StatusServiceUnavailable)
+	health := responses.Health{}
+	decode(t, rec, &health)
+	assert.Equal(t, health.Checks["migrations"].Status, responses.StatusDown)
+
+	// The probe does not create the schema version table
+	assert.Equal(t, db.HasTable("schema_migrations"), false)
+}
+
+func TestReadyzHidesErrors(t *testing.T) {
+	logs := captureLogs(t)
+	ts := newTestServer(t)
+	ts.server.DB.Close()
+
+	rec := ts.do("GET", "/readyz", "", nil)
+	assert.Equal(t, rec.Code, http.StatusServiceUnavailable)
+	health := responses.Health{}
+	decode(t, rec, &health)
+	assert.Equal(t, health.Checks["database"].Error, "database unreachable")
+	assert.Equal(t, health.Checks["migrations"].Error, "migration status unavailable")
+	assert.Equal(t, strings.Contains(rec.Body.String(), "closed"), false)
+
+	// The cause is logged instead
+	failed := records(t, logs, "readiness check failed")
+	assert.Equal(t, len(failed), 2)
+	assert.MatchRegex(t, fmt.Sprint(failed[0]["error"]), "closed")
+}
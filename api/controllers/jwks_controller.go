@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+// JWKS publishes the public keys used to sign access tokens
+func (server *Server) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := auth.Keys()
+	if err != nil {
+		responses.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	responses.ResponseJSON(w, http.StatusOK, keys.JWKS())
+}
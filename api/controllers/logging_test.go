@@ -0,0 +1,105 @@
+package controllers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/controllers"
+	"github.com/antonio91capa/go-apirest/api/logging"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+// captureLogs makes the default logger write JSON debug records to the
+// returned buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	logger, err := logging.New(config.Log{Level: "debug", Format: "json"}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return buf
+}
+
+// records decodes the JSON records of buf with the message msg.
+func records(t *testing.T, buf *bytes.Buffer, msg string) []map[string]interface{} {
+	t.Helper()
+	found := []map[string]interface{}{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		record := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decoding %q: %v", scanner.Text(), err)
+		}
+		if record["msg"] == msg {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+func (ts *testServer) doWithRequestID(method, path, token, id string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(middlewares.RequestIDHeader, id)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSQLLogsCarryRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	ts := newTestServer(t)
+	buf.Reset()
+
+	rec := ts.doWithRequestID("GET", ts.expand("/v1/users/{alice}"), "", "sql-request")
+	assert.Equal(t, rec.Code, http.StatusOK)
+
+	statements := records(t, buf, "sql")
+	assert.NotEqual(t, len(statements), 0)
+	for _, record := range statements {
+		assert.Equal(t, record["request_id"], "sql-request")
+	}
+}
+
+func TestInternalErrorLogCarriesRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	memory := repository.NewMemory()
+	server := &controllers.Server{
+		Users:      brokenUsers{memory.Users()},
+		Posts:      memory.Posts(),
+		Transactor: memory,
+		Logger:     slog.Default(),
+	}
+	server.Setup(nil)
+	ts := &testServer{t: t, server: server, handler: server.Handler()}
+	token, err := auth.CreateToken(1, models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := ts.doWithRequestID("GET", "/v1/users/1", token, "broken-request")
+	assert.Equal(t, rec.Code, http.StatusInternalServerError)
+
+	errors := records(t, buf, "internal error")
+	assert.Equal(t, len(errors), 1)
+	assert.Equal(t, errors[0]["request_id"], "broken-request")
+	assert.Equal(t, errors[0]["error"], errDriver.Error())
+}
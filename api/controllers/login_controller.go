@@ -1,59 +1,43 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 
-	"github.com/antonio91capa/go-apirest/api/auth"
 	"github.com/antonio91capa/go-apirest/api/exception"
 	"github.com/antonio91capa/go-apirest/api/models"
 	"github.com/antonio91capa/go-apirest/api/responses"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 
-	user.Prepare()
-	err = user.Validate("login")
+	tokens, err := server.SignIn(r.Context(), user.Email, user.Password)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	token, err := server.SignIn(user.Email, user.Password)
-	if err != nil {
-		formattedError := exception.FormatError(err.Error())
-		responses.Error(w, http.StatusUnprocessableEntity, formattedError)
-		return
-	}
-
-	responses.ResponseJSON(w, http.StatusOK, token)
+	responses.ResponseJSON(w, http.StatusOK, tokens)
 }
 
-func (server *Server) SignIn(email, password string) (string, error) {
-	var err error
-	user := models.User{}
-	err = server.DB.Debug().Model(models.User{}).Where("email=?", email).Take(&user).Error
+func (server *Server) SignIn(ctx context.Context, email, password string) (*responses.Tokens, error) {
+	user, err := server.users.Authenticate(ctx, email, password)
 	if err != nil {
-		return "", err
-	}
-	err = models.VerifyPassword(user.Password, password)
-	if err != nil && err == bcrypt.ErrMismatchedHashAndPassword {
-		return "", err
+		return nil, err
 	}
-
-	return auth.CreateToken(user.ID)
+	return server.tokens.Issue(ctx, user)
 }
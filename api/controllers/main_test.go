@@ -0,0 +1,261 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/controllers"
+	"github.com/antonio91capa/go-apirest/api/database"
+	"github.com/antonio91capa/go-apirest/api/migrations"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+// password is the password of every fixture user, hashed once in TestMain
+const password = "longenough1"
+
+var passwordHash string
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	keys, err := auth.NewKeyManagerFromConfig(config.JWT{Algorithm: "HS256", Secret: "test-secret"})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	auth.SetKeyManager(keys)
+
+	hash, err := models.Hash(password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	passwordHash = string(hash)
+
+	os.Exit(m.Run())
+}
+
+// testServer is a controllers.Server with a few users and posts:
+//
+//	alice (user), bob (user), carol (moderator), dave (admin)
+//	"Alice post" by alice, "Bob post" by bob
+type testServer struct {
+	t       *testing.T
+	server  *controllers.Server
+	handler http.Handler
+	users   map[string]*models.User
+	posts   map[string]*models.Post
+}
+
+// newTestServer boots the server on a migrated SQLite :memory: database,
+// without the unversioned aliases.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWith(t, config.API{})
+}
+
+func newTestServerWith(t *testing.T, api config.API) *testServer {
+	t.Helper()
+	db, err := database.Open(config.Database{Driver: "sqlite", Name: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrations.New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	server := &controllers.Server{Logger: slog.Default(), API: api}
+	server.Setup(db)
+	t.Cleanup(func() { server.Close() })
+	return seed(t, server)
+}
+
+// newMemoryTestServer boots the server on the in-memory repositories, without
+// a database.
+func newMemoryTestServer(t *testing.T) *testServer {
+	t.Helper()
+	memory := repository.NewMemory()
+	server := &controllers.Server{
+		Users:         memory.Users(),
+		Posts:         memory.Posts(),
+		RefreshTokens: memory.RefreshTokens(),
+		Transactor:    memory,
+		Logger:        slog.Default(),
+	}
+	server.Setup(nil)
+	return seed(t, server)
+}
+
+// forEachBackend runs test as a subtest on the SQLite and the in-memory repositories.
+func forEachBackend(t *testing.T, test func(t *testing.T, ts *testServer)) {
+	t.Helper()
+	backends := []struct {
+		name      string
+		newServer func(t *testing.T) *testServer
+	}{
+		{"sqlite", newTestServer},
+		{"memory", newMemoryTestServer},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.newServer(t))
+		})
+	}
+}
+
+func seed(t *testing.T, server *controllers.Server) *testServer {
+	t.Helper()
+	ts := &testServer{
+		t:       t,
+		server:  server,
+		handler: server.Handler(),
+		users:   map[string]*models.User{},
+		posts:   map[string]*models.Post{},
+	}
+	ctx := context.Background()
+
+	roles := []struct{ nickname, role string }{
+		{"alice", models.RoleUser},
+		{"bob", models.RoleUser},
+		{"carol", models.RoleModerator},
+		{"dave", models.RoleAdmin},
+	}
+	for _, r := range roles {
+		user, err := server.Users.Create(ctx, &models.User{
+			Nickname: r.nickname,
+			Email:    r.nickname + "@mail.com",
+			Password: passwordHash,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.role != models.RoleUser {
+			if user, err = server.Users.UpdateRole(ctx, user.ID, r.role); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ts.users[r.nickname] = user
+	}
+
+	for _, nickname := range []string{"alice", "bob"} {
+		title := strings.ToUpper(nickname[:1]) + nickname[1:] + " post"
+		post, err := server.Posts.Create(ctx, &models.Post{
+			Title:    title,
+			Content:  "Content of " + title,
+			AuthorID: ts.users[nickname].ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.posts[nickname] = post
+	}
+	return ts
+}
+
+// token returns an access token of the fixture user nickname with its role.
+func (ts *testServer) token(nickname string) string {
+	ts.t.Helper()
+	user, ok := ts.users[nickname]
+	if !ok {
+		ts.t.Fatalf("unknown fixture user %q", nickname)
+	}
+	token, err := auth.CreateToken(user.ID, user.Role)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return token
+}
+
+// do sends a request with body encoded as JSON, or sent as is when it is a
+// string, and the bearer token when not empty.
+func (ts *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		b, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals the body of rec into v.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// problem decodes the problem document of an error response.
+func problem(t *testing.T, rec *httptest.ResponseRecorder) responses.Problem {
+	t.Helper()
+	p := responses.Problem{}
+	decode(t, rec, &p)
+	return p
+}
+
+// routes returns the "METHOD /template" of every route of the router.
+func routes(t *testing.T, router *mux.Router) map[string]bool {
+	t.Helper()
+	routes := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// The routes of the subrouters are walked on their own
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes[method+" "+template] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
+
+func sorted(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// parsePagination reads the limit, offset and cursor query parameters.
+func parsePagination(r *http.Request) (models.Pagination, error) {
+	page := models.Pagination{}
+	query := r.URL.Query()
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return page, exception.InvalidParameter("limit", "integer", "Invalid limit")
+		}
+		page.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return page, exception.InvalidParameter("offset", "integer", "Invalid offset")
+		}
+		page.Offset = n
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		if page.Offset > 0 {
+			return page, exception.InvalidParameter("cursor", "exclusive", "Cursor and offset cannot be combined")
+		}
+		c, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = c
+	}
+
+	page.Normalize()
+	return page, nil
+}
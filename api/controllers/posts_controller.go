@@ -2,13 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 
-	"github.com/antonio91capa/go-apirest/api/auth"
 	"github.com/antonio91capa/go-apirest/api/exception"
 	"github.com/antonio91capa/go-apirest/api/models"
 	"github.com/antonio91capa/go-apirest/api/responses"
@@ -17,54 +15,66 @@ import (
 
 // ************** Create New Post
 func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
+	expansion, err := parsePostExpansion(r)
+	if err != nil {
+		responses.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 	post := models.Post{}
 	err = json.Unmarshal(body, &post)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 
-	post.Prepare()
-	err = post.Validate()
+	postCreated, err := server.posts.Create(r.Context(), actor(r), post, expansion)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	uid, err := auth.ExtractTokenID(r)
+
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, postCreated.ID))
+	responses.ResponseJSON(w, http.StatusCreated, responses.NewPost(postCreated))
+}
+
+// ************************* Get All Posts
+func (server *Server) GetPosts(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePagination(r)
 	if err != nil {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
-	if uid != post.AuthorID {
-		responses.Error(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+
+	filter, err := parsePostFilter(r)
+	if err != nil {
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	postCreated, err := post.SavePost(server.DB)
+	expansion, err := parsePostExpansion(r)
 	if err != nil {
-		formattedError := exception.FormatError(err.Error())
-		responses.Error(w, http.StatusInternalServerError, formattedError)
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, postCreated.ID))
-	responses.ResponseJSON(w, http.StatusCreated, postCreated)
-}
-
-// ************************* Get All Posts
-func (server *Server) GetPosts(w http.ResponseWriter, r *http.Request) {
-	post := models.Post{}
-	posts, err := post.FindAllPosts(server.DB)
+	posts, info, err := server.posts.List(r.Context(), filter, page, expansion)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	responses.ResponseJSON(w, http.StatusOK, posts)
+	responses.ResponseJSON(w, http.StatusOK, responses.Page{
+		Data:       responses.NewPosts(posts),
+		Total:      info.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: info.NextCursor,
+	})
 }
 
 // ************************** Get Post By ID
@@ -72,18 +82,23 @@ func (server *Server) GetPostById(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	pid, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
 
-	post := models.Post{}
-	postReceived, err := post.FindPostByID(server.DB, pid)
+	expansion, err := parsePostExpansion(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	responses.ResponseJSON(w, http.StatusOK, postReceived)
+	postReceived, err := server.posts.Get(r.Context(), pid, expansion)
+	if err != nil {
+		responses.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses.ResponseJSON(w, http.StatusOK, responses.NewPost(postReceived))
 }
 
 // **************************** Update Post
@@ -93,69 +108,36 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	// Check if the post id is valid
 	pid, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
 
-	// Check if the auth token is valid and get the user id
-	uid, err := auth.ExtractTokenID(r)
+	expansion, err := parsePostExpansion(r)
 	if err != nil {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-		return
-	}
-
-	// Check if the post exist
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id=?", pid).Take(&post).Error
-	if err != nil {
-		responses.Error(w, http.StatusNotFound, errors.New("Post Not Found"))
-		return
-	}
-
-	// If a user attempt to update a post not belonging to him
-	if uid != post.AuthorID {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	// Read the data posted
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
-
-	// Start processing the request data
 	postUpdate := models.Post{}
 	err = json.Unmarshal(body, &postUpdate)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
-		return
-	}
-
-	// Check if the request user id is equal to the one gotten from token
-	if uid != postUpdate.AuthorID {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-		return
-	}
-
-	postUpdate.Prepare()
-	err = postUpdate.Validate()
-	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 
-	postUpdate.ID = post.ID //this is important to tell the model the post id to update, the other update field are set above
-
-	postUpdated, err := postUpdate.UpdatePost(server.DB)
+	postUpdated, err := server.posts.Update(r.Context(), actor(r), pid, postUpdate, expansion)
 	if err != nil {
-		formattedError := exception.FormatError(err.Error())
-		responses.Error(w, http.StatusInternalServerError, formattedError)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	responses.ResponseJSON(w, http.StatusOK, postUpdated)
+	responses.ResponseJSON(w, http.StatusOK, responses.NewPost(postUpdated))
 }
 
 // ********************************* Delete Post
@@ -165,28 +147,13 @@ func (server *Server) DeletePost(w http.ResponseWriter, r *http.Request) {
 	// Is a valid post id given to us
 	pid, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
-		return
-	}
-
-	// Is this user authenticated
-	uid, err := auth.ExtractTokenID(r)
-	if err != nil {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-		return
-	}
-
-	//Check if the post exist
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id=?", pid).Take(&post).Error
-	if err != nil {
-		responses.Error(w, http.StatusNotFound, errors.New("Unauthorized"))
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
 
-	_, err = post.DeletePost(server.DB, pid, uid)
+	err = server.posts.Delete(r.Context(), actor(r), pid)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -0,0 +1,64 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostsCursorPagination(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		token := ts.token("bob")
+		for i := 1; i <= 5; i++ {
+			rec := ts.do("POST", "/v1/posts", token, body{
+				"title":     fmt.Sprintf("Page post %d", i),
+				"content":   "Content",
+				"author_id": ts.users["bob"].ID,
+			})
+			assert.Equal(t, rec.Code, http.StatusCreated)
+		}
+
+		titles := []string{}
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			path := "/v1/posts?limit=2&sort=-title&author_id=" + fmt.Sprint(ts.users["bob"].ID)
+			if cursor != "" {
+				path += "&cursor=" + url.QueryEscape(cursor)
+			}
+			rec := ts.do("GET", path, "", nil)
+			assert.Equal(t, rec.Code, http.StatusOK)
+			page := struct {
+				Data []struct {
+					Title string `json:"title"`
+				} `json:"data"`
+				Total      int    `json:"total"`
+				NextCursor string `json:"next_cursor"`
+			}{}
+			decode(t, rec, &page)
+			assert.Equal(t, page.Total, 6)
+			for _, post := range page.Data {
+				titles = append(titles, post.Title)
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		assert.Equal(t, titles, []string{
+			"Page post 5", "Page post 4", "Page post 3", "Page post 2", "Page post 1", "Bob post",
+		})
+
+		// A cursor only continues the sort it was issued for
+		rec := ts.do("GET", "/v1/posts?limit=2&sort=-title", "", nil)
+		page := struct {
+			NextCursor string `json:"next_cursor"`
+		}{}
+		decode(t, rec, &page)
+		rec = ts.do("GET", "/v1/posts?sort=title&cursor="+url.QueryEscape(page.NextCursor), "", nil)
+		assert.Equal(t, rec.Code, http.StatusBadRequest)
+		assert.Equal(t, problem(t, rec).Code, "query.invalid_cursor")
+	})
+}
@@ -1,25 +1,70 @@
 package controllers
 
-import "github.com/antonio91capa/go-apirest/api/middlewares"
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+)
 
 func (s *Server) initializeRoutes() {
 	// Home Route
 	s.Router.HandleFunc("/", middlewares.SetMiddlewareJSON(s.Home)).Methods("GET")
 
+	// Probes and build info
+	s.Router.HandleFunc("/healthz", middlewares.SetMiddlewareJSON(s.Healthz)).Methods("GET")
+	s.Router.HandleFunc("/readyz", middlewares.SetMiddlewareJSON(s.Readyz)).Methods("GET")
+	s.Router.HandleFunc("/version", middlewares.SetMiddlewareJSON(s.Version)).Methods("GET")
+	if s.Metrics != nil {
+		s.Router.Handle("/metrics", promhttp.HandlerFor(s.Metrics, promhttp.HandlerOpts{})).Methods("GET")
+	}
+
+	// API documentation
+	s.Router.HandleFunc("/openapi.json", s.OpenAPI).Methods("GET")
+	s.Router.HandleFunc("/docs", s.Docs).Methods("GET")
+	s.Router.HandleFunc("/docs/{asset}", s.DocsAsset).Methods("GET")
+
+	// Token verification keys, at their well-known path
+	s.Router.HandleFunc("/.well-known/jwks.json", middlewares.SetMiddlewareJSON(s.JWKS)).Methods("GET")
+
+	// Versioned API, a v2 gets its own initializeV2Routes(s.APIVersion("v2"))
+	s.initializeV1Routes(s.APIVersion("v1"))
+
+	// The unversioned paths of before /v1 existed keep serving v1
+	if s.API.Aliases {
+		aliases := s.Router.NewRoute().Subrouter()
+		aliases.Use(middlewares.Deprecation{
+			Since:  s.API.AliasDeprecation,
+			Sunset: s.API.AliasSunset,
+			Successor: func(r *http.Request) string {
+				return "/v1" + r.URL.RequestURI()
+			},
+		}.Handler)
+		s.initializeV1Routes(aliases)
+	}
+}
+
+// initializeV1Routes registers the v1 routes on r, the /v1 subrouter or the aliases.
+func (s *Server) initializeV1Routes(r *mux.Router) {
 	// Login Route
-	s.Router.HandleFunc("/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
+	r.HandleFunc("/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
+	r.HandleFunc("/token/refresh", middlewares.SetMiddlewareJSON(s.RefreshToken)).Methods("POST")
+	r.HandleFunc("/logout", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.Logout))).Methods("POST")
 
 	// Users Routes
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.GetUsers)).Methods("GET")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(s.GetUserByID)).Methods("GET")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
+	r.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
+	r.HandleFunc("/users", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetUsers))).Methods("GET")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.GetUserByID))).Methods("GET")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
+	r.HandleFunc("/users/{id}/role", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUserRole))).Methods("PUT")
 
 	//Posts Routes
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPostById)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost))).Methods("PUT")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeletePost)).Methods("DELETE")
+	r.HandleFunc("/posts", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreatePost))).Methods("POST")
+	r.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPostById)).Methods("GET")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost))).Methods("PUT")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeletePost)).Methods("DELETE")
 }
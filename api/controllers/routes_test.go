@@ -0,0 +1,632 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// routeCase is one request against the fixture of testServer. The path may
+// reference the fixture with {alice} for the id of a user and {alice_post}
+// for the id of a post.
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	// as is the fixture user sending the request, anonymous when empty
+	as string
+	// token overrides the access token of as
+	token string
+	// body is sent as JSON, as is when it is a string, or built from the
+	// fixture when it is a func(*testServer) interface{}
+	body interface{}
+	// database is set for the routes only served with a database
+	database bool
+	// setup changes the fixture before the request is sent
+	setup func(t *testing.T, ts *testServer)
+
+	status int
+	// code is the code of the expected problem document
+	code  string
+	check func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder)
+}
+
+func (ts *testServer) expand(path string) string {
+	for nickname, user := range ts.users {
+		path = strings.ReplaceAll(path, "{"+nickname+"}", fmt.Sprint(user.ID))
+	}
+	for nickname, post := range ts.posts {
+		path = strings.ReplaceAll(path, "{"+nickname+"_post}", fmt.Sprint(post.ID))
+	}
+	return path
+}
+
+func (c routeCase) run(t *testing.T, ts *testServer) {
+	if c.setup != nil {
+		c.setup(t, ts)
+	}
+	token := c.token
+	if token == "" && c.as != "" {
+		token = ts.token(c.as)
+	}
+	body := c.body
+	if build, ok := body.(func(ts *testServer) interface{}); ok {
+		body = build(ts)
+	}
+	rec := ts.do(c.method, ts.expand(c.path), token, body)
+
+	assert.Equal(t, rec.Code, c.status)
+	if c.code != "" {
+		p := problem(t, rec)
+		assert.Equal(t, p.Code, c.code)
+		assert.Equal(t, p.Status, c.status)
+		assert.Equal(t, rec.Header().Get("Content-Type"), "application/problem+json")
+	}
+	if c.check != nil {
+		c.check(t, ts, rec)
+	}
+}
+
+type body map[string]interface{}
+
+func newUser(nickname string) body {
+	return body{"nickname": nickname, "email": nickname + "@mail.com", "password": password}
+}
+
+// newPost is the body of a post by the fixture user author, resolved once the
+// fixture exists.
+func newPost(title, author string) func(ts *testServer) interface{} {
+	return func(ts *testServer) interface{} {
+		return body{"title": title, "content": "Content of " + title, "author_id": ts.users[author].ID}
+	}
+}
+
+var routeCases = []routeCase{
+	// Home, probes and build info
+	{name: "home", method: "GET", path: "/", status: http.StatusOK},
+	{name: "healthz", method: "GET", path: "/healthz", status: http.StatusOK},
+	{name: "readyz", method: "GET", path: "/readyz", status: http.StatusOK, database: true},
+	{name: "version", method: "GET", path: "/version", status: http.StatusOK},
+	{name: "metrics", method: "GET", path: "/metrics", status: http.StatusOK},
+	{name: "jwks", method: "GET", path: "/.well-known/jwks.json", status: http.StatusOK},
+	{name: "openapi", method: "GET", path: "/openapi.json", status: http.StatusOK},
+	{
+		name: "docs", method: "GET", path: "/docs", status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+			assert.MatchRegex(t, rec.Header().Get("Content-Security-Policy"), `script-src 'self'`)
+			assert.Equal(t, strings.Contains(rec.Body.String(), "https://"), false)
+		},
+	},
+	{
+		name: "docs asset", method: "GET", path: "/docs/swagger-ui-bundle.js", status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			assert.MatchRegex(t, rec.Header().Get("Content-Type"), `^text/javascript`)
+			assert.Equal(t, strings.Contains(rec.Body.String(), "SwaggerUIBundle"), true)
+		},
+	},
+	{name: "docs asset not found", method: "GET", path: "/docs/openapi.json", status: http.StatusNotFound},
+
+	// Login
+	{
+		name: "login", method: "POST", path: "/v1/login",
+		body:   body{"email": "alice@mail.com", "password": password},
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			tokens := map[string]interface{}{}
+			decode(t, rec, &tokens)
+			assert.Equal(t, tokens["token_type"], "Bearer")
+			assert.NotEqual(t, tokens["access_token"], "")
+			assert.NotEqual(t, tokens["refresh_token"], "")
+		},
+	},
+	{
+		name: "login wrong password", method: "POST", path: "/v1/login",
+		body:   body{"email": "alice@mail.com", "password": "wrongpassword1"},
+		status: http.StatusUnauthorized, code: "auth.invalid_credentials",
+	},
+	{
+		name: "login unknown email", method: "POST", path: "/v1/login",
+		body:   body{"email": "nobody@mail.com", "password": password},
+		status: http.StatusUnauthorized, code: "auth.invalid_credentials",
+	},
+	{
+		name: "login without credentials", method: "POST", path: "/v1/login",
+		body:   body{},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "login malformed body", method: "POST", path: "/v1/login",
+		body:   "{",
+		status: http.StatusUnprocessableEntity, code: "request.invalid_body",
+	},
+
+	// Refresh and logout
+	{
+		name: "refresh without token", method: "POST", path: "/v1/token/refresh",
+		body:   body{},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "refresh unknown token", method: "POST", path: "/v1/token/refresh",
+		body:   body{"refresh_token": "unknown"},
+		status: http.StatusUnauthorized, code: "auth.refresh_token_invalid",
+	},
+	{
+		name: "refresh malformed body", method: "POST", path: "/v1/token/refresh",
+		body:   "not json",
+		status: http.StatusUnprocessableEntity, code: "request.invalid_body",
+	},
+	{name: "logout", method: "POST", path: "/v1/logout", as: "alice", status: http.StatusNoContent},
+	{
+		name: "logout anonymous", method: "POST", path: "/v1/logout",
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "logout invalid token", method: "POST", path: "/v1/logout", token: "not.a.token",
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+
+	// Users
+	{
+		name: "create user", method: "POST", path: "/v1/users",
+		body:   newUser("erin"),
+		status: http.StatusCreated,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			user := map[string]interface{}{}
+			decode(t, rec, &user)
+			assert.Equal(t, user["nickname"], "erin")
+			assert.Equal(t, user["email"], "erin@mail.com")
+			assert.Equal(t, user["role"], "user")
+			_, leaked := user["password"]
+			assert.Equal(t, leaked, false)
+		},
+	},
+	{
+		name: "create user nickname taken", method: "POST", path: "/v1/users",
+		body:   body{"nickname": "alice", "email": "other@mail.com", "password": password},
+		status: http.StatusConflict, code: "user.nickname_taken",
+	},
+	{
+		name: "create user email taken", method: "POST", path: "/v1/users",
+		body:   body{"nickname": "other", "email": "alice@mail.com", "password": password},
+		status: http.StatusConflict, code: "user.email_taken",
+	},
+	{
+		name: "create user invalid", method: "POST", path: "/v1/users",
+		body:   body{"nickname": "erin", "email": "not an email", "password": "short"},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			fields := map[string]bool{}
+			for _, e := range problem(t, rec).Errors {
+				fields[e.Field] = true
+			}
+			assert.Equal(t, fields["email"], true)
+			assert.Equal(t, fields["password"], true)
+		},
+	},
+	{
+		name: "create user malformed body", method: "POST", path: "/v1/users",
+		body:   "[]",
+		status: http.StatusUnprocessableEntity, code: "request.invalid_body",
+	},
+	{
+		name: "list users", method: "GET", path: "/v1/users?limit=2",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			page := struct {
+				Data       []map[string]interface{} `json:"data"`
+				Total      int                      `json:"total"`
+				NextCursor string                   `json:"next_cursor"`
+			}{}
+			decode(t, rec, &page)
+			assert.Equal(t, page.Total, 4)
+			assert.Equal(t, len(page.Data), 2)
+			assert.NotEqual(t, page.NextCursor, "")
+			_, leaked := page.Data[0]["email"]
+			assert.Equal(t, leaked, false)
+		},
+	},
+	{
+		name: "list users invalid limit", method: "GET", path: "/v1/users?limit=0",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "list users invalid cursor", method: "GET", path: "/v1/users?cursor=bogus",
+		status: http.StatusBadRequest, code: "query.invalid_cursor",
+	},
+	{
+		name: "get user", method: "GET", path: "/v1/users/{alice}",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			user := map[string]interface{}{}
+			decode(t, rec, &user)
+			assert.Equal(t, user["nickname"], "alice")
+			_, leaked := user["email"]
+			assert.Equal(t, leaked, false)
+		},
+	},
+	{
+		name: "get user as self", method: "GET", path: "/v1/users/{alice}", as: "alice",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			user := map[string]interface{}{}
+			decode(t, rec, &user)
+			assert.Equal(t, user["email"], "alice@mail.com")
+		},
+	},
+	{
+		name: "get user not found", method: "GET", path: "/v1/users/999",
+		status: http.StatusNotFound, code: "user.not_found",
+	},
+	{
+		name: "get user invalid id", method: "GET", path: "/v1/users/abc",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "update user as self", method: "PUT", path: "/v1/users/{alice}", as: "alice",
+		body:   newUser("alicia"),
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			user := map[string]interface{}{}
+			decode(t, rec, &user)
+			assert.Equal(t, user["nickname"], "alicia")
+			assert.Equal(t, user["email"], "alicia@mail.com")
+		},
+	},
+	{
+		name: "update user as admin", method: "PUT", path: "/v1/users/{alice}", as: "dave",
+		body:   newUser("alicia"),
+		status: http.StatusOK,
+	},
+	{
+		name: "update user anonymous", method: "PUT", path: "/v1/users/{alice}",
+		body:   newUser("alicia"),
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "update other user", method: "PUT", path: "/v1/users/{alice}", as: "bob",
+		body:   newUser("alicia"),
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "update user as moderator", method: "PUT", path: "/v1/users/{alice}", as: "carol",
+		body:   newUser("alicia"),
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "update user invalid", method: "PUT", path: "/v1/users/{alice}", as: "alice",
+		body:   body{"nickname": "alice", "email": "alice@mail.com"},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "update user email taken", method: "PUT", path: "/v1/users/{alice}", as: "alice",
+		body:   body{"nickname": "alice", "email": "bob@mail.com", "password": password},
+		status: http.StatusConflict, code: "user.email_taken",
+	},
+	{
+		name: "update user not found", method: "PUT", path: "/v1/users/999", as: "dave",
+		body:   newUser("nobody"),
+		status: http.StatusNotFound, code: "user.not_found",
+	},
+	{
+		name: "update user invalid id", method: "PUT", path: "/v1/users/abc", as: "dave",
+		body:   newUser("nobody"),
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "delete user as self", method: "DELETE", path: "/v1/users/{alice}", as: "alice",
+		status: http.StatusNoContent,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, ts.do("GET", ts.expand("/v1/users/{alice}"), "", nil).Code, http.StatusNotFound)
+			// Posts are deleted with their author, the posts of the other users are kept
+			assert.Equal(t, ts.do("GET", ts.expand("/v1/posts/{alice_post}"), "", nil).Code, http.StatusNotFound)
+			rec = ts.do("GET", ts.expand("/v1/posts?author_id={alice}"), "", nil)
+			page := struct {
+				Total int `json:"total"`
+			}{}
+			decode(t, rec, &page)
+			assert.Equal(t, page.Total, 0)
+			assert.Equal(t, ts.do("GET", ts.expand("/v1/posts/{bob_post}"), "", nil).Code, http.StatusOK)
+		},
+	},
+	{name: "delete user as admin", method: "DELETE", path: "/v1/users/{alice}", as: "dave", status: http.StatusNoContent},
+	{
+		name: "delete user anonymous", method: "DELETE", path: "/v1/users/{alice}",
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "delete other user", method: "DELETE", path: "/v1/users/{alice}", as: "bob",
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "delete user not found", method: "DELETE", path: "/v1/users/999", as: "dave",
+		status: http.StatusNotFound, code: "user.not_found",
+	},
+	{
+		name: "delete user invalid id", method: "DELETE", path: "/v1/users/abc", as: "dave",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "update role as admin", method: "PUT", path: "/v1/users/{alice}/role", as: "dave",
+		body:   body{"role": "moderator"},
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			user := map[string]interface{}{}
+			decode(t, rec, &user)
+			assert.Equal(t, user["role"], "moderator")
+		},
+	},
+	{
+		name: "update role anonymous", method: "PUT", path: "/v1/users/{alice}/role",
+		body:   body{"role": "admin"},
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "update own role", method: "PUT", path: "/v1/users/{alice}/role", as: "alice",
+		body:   body{"role": "admin"},
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "update role as moderator", method: "PUT", path: "/v1/users/{alice}/role", as: "carol",
+		body:   body{"role": "moderator"},
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "update role invalid", method: "PUT", path: "/v1/users/{alice}/role", as: "dave",
+		body:   body{"role": "root"},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "update role not found", method: "PUT", path: "/v1/users/999/role", as: "dave",
+		body:   body{"role": "moderator"},
+		status: http.StatusNotFound, code: "user.not_found",
+	},
+	{
+		name: "update role invalid id", method: "PUT", path: "/v1/users/abc/role", as: "dave",
+		body:   body{"role": "moderator"},
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+
+	// Posts
+	{
+		name: "create post", method: "POST", path: "/v1/posts?include=author", as: "alice",
+		body:   newPost("New post", "alice"),
+		status: http.StatusCreated,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			post := struct {
+				Title    string                 `json:"title"`
+				AuthorID uint32                 `json:"author_id"`
+				Author   map[string]interface{} `json:"author"`
+			}{}
+			decode(t, rec, &post)
+			assert.Equal(t, post.Title, "New post")
+			assert.Equal(t, post.AuthorID, ts.users["alice"].ID)
+			assert.Equal(t, post.Author["nickname"], "alice")
+		},
+	},
+	{
+		name: "create post anonymous", method: "POST", path: "/v1/posts",
+		body:   newPost("New post", "alice"),
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "create post for other author", method: "POST", path: "/v1/posts", as: "bob",
+		body:   newPost("New post", "alice"),
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "create post invalid", method: "POST", path: "/v1/posts", as: "alice",
+		body:   body{"title": "", "content": ""},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "create post title taken", method: "POST", path: "/v1/posts", as: "alice",
+		body:   newPost("Alice post", "alice"),
+		status: http.StatusConflict, code: "post.title_taken",
+	},
+	{
+		name: "create post invalid include", method: "POST", path: "/v1/posts?include=comments", as: "alice",
+		body:   newPost("New post", "alice"),
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		// The token outlives its user, deleted meanwhile
+		name: "create post deleted author", method: "POST", path: "/v1/posts", as: "alice",
+		body: newPost("New post", "alice"),
+		setup: func(t *testing.T, ts *testServer) {
+			if err := ts.server.Users.Delete(context.Background(), ts.users["alice"].ID); err != nil {
+				t.Fatal(err)
+			}
+		},
+		status: http.StatusUnprocessableEntity, code: "post.unknown_author",
+	},
+	{
+		name: "create post malformed body", method: "POST", path: "/v1/posts", as: "alice",
+		body:   "{",
+		status: http.StatusUnprocessableEntity, code: "request.invalid_body",
+	},
+	{
+		name: "list posts", method: "GET", path: "/v1/posts?include=author&sort=title",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			page := struct {
+				Data []struct {
+					Title  string                 `json:"title"`
+					Author map[string]interface{} `json:"author"`
+				} `json:"data"`
+				Total int `json:"total"`
+			}{}
+			decode(t, rec, &page)
+			assert.Equal(t, page.Total, 2)
+			assert.Equal(t, page.Data[0].Title, "Alice post")
+			assert.Equal(t, page.Data[0].Author["nickname"], "alice")
+			assert.Equal(t, page.Data[1].Title, "Bob post")
+		},
+	},
+	{
+		name: "list posts by author", method: "GET", path: "/v1/posts?author_id={bob}",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			page := struct {
+				Total int `json:"total"`
+			}{}
+			decode(t, rec, &page)
+			assert.Equal(t, page.Total, 1)
+		},
+	},
+	{
+		name: "list posts invalid sort", method: "GET", path: "/v1/posts?sort=nickname",
+		status: http.StatusBadRequest, code: "query.invalid_sort",
+	},
+	{
+		name: "list posts invalid author", method: "GET", path: "/v1/posts?author_id=x",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "get post", method: "GET", path: "/v1/posts/{alice_post}",
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			post := map[string]interface{}{}
+			decode(t, rec, &post)
+			assert.Equal(t, post["title"], "Alice post")
+			_, expanded := post["author"]
+			assert.Equal(t, expanded, false)
+		},
+	},
+	{
+		name: "get post not found", method: "GET", path: "/v1/posts/999",
+		status: http.StatusNotFound, code: "post.not_found",
+	},
+	{
+		name: "get post invalid id", method: "GET", path: "/v1/posts/abc",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{
+		name: "update post as author", method: "PUT", path: "/v1/posts/{alice_post}", as: "alice",
+		body:   body{"title": "Edited", "content": "Edited content"},
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			post := struct {
+				Title    string `json:"title"`
+				AuthorID uint32 `json:"author_id"`
+			}{}
+			decode(t, rec, &post)
+			assert.Equal(t, post.Title, "Edited")
+			assert.Equal(t, post.AuthorID, ts.users["alice"].ID)
+		},
+	},
+	{
+		name: "update post as moderator", method: "PUT", path: "/v1/posts/{alice_post}", as: "carol",
+		body:   body{"title": "Moderated", "content": "Moderated content"},
+		status: http.StatusOK,
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			post := struct {
+				AuthorID uint32 `json:"author_id"`
+			}{}
+			decode(t, rec, &post)
+			// Moderators edit posts without taking them over
+			assert.Equal(t, post.AuthorID, ts.users["alice"].ID)
+		},
+	},
+	{
+		name: "update post anonymous", method: "PUT", path: "/v1/posts/{alice_post}",
+		body:   body{"title": "Edited", "content": "Edited content"},
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "update other author post", method: "PUT", path: "/v1/posts/{alice_post}", as: "bob",
+		body:   body{"title": "Edited", "content": "Edited content"},
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "update post invalid", method: "PUT", path: "/v1/posts/{alice_post}", as: "alice",
+		body:   body{"title": "Edited"},
+		status: http.StatusUnprocessableEntity, code: "validation.failed",
+	},
+	{
+		name: "update post title taken", method: "PUT", path: "/v1/posts/{alice_post}", as: "alice",
+		body:   body{"title": "Bob post", "content": "Edited content"},
+		status: http.StatusConflict, code: "post.title_taken",
+		check: func(t *testing.T, ts *testServer, rec *httptest.ResponseRecorder) {
+			post := map[string]interface{}{}
+			decode(t, ts.do("GET", ts.expand("/v1/posts/{alice_post}"), "", nil), &post)
+			assert.Equal(t, post["title"], "Alice post")
+		},
+	},
+	{
+		name: "update post not found", method: "PUT", path: "/v1/posts/999", as: "dave",
+		body:   body{"title": "Edited", "content": "Edited content"},
+		status: http.StatusNotFound, code: "post.not_found",
+	},
+	{
+		name: "update post invalid id", method: "PUT", path: "/v1/posts/abc", as: "alice",
+		body:   body{"title": "Edited", "content": "Edited content"},
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+	{name: "delete post as author", method: "DELETE", path: "/v1/posts/{alice_post}", as: "alice", status: http.StatusNoContent},
+	{name: "delete post as moderator", method: "DELETE", path: "/v1/posts/{alice_post}", as: "carol", status: http.StatusNoContent},
+	{
+		name: "delete post anonymous", method: "DELETE", path: "/v1/posts/{alice_post}",
+		status: http.StatusUnauthorized, code: "auth.unauthorized",
+	},
+	{
+		name: "delete other author post", method: "DELETE", path: "/v1/posts/{alice_post}", as: "bob",
+		status: http.StatusForbidden, code: "auth.forbidden",
+	},
+	{
+		name: "delete post not found", method: "DELETE", path: "/v1/posts/999", as: "alice",
+		status: http.StatusNotFound, code: "post.not_found",
+	},
+	{
+		name: "delete post invalid id", method: "DELETE", path: "/v1/posts/abc", as: "alice",
+		status: http.StatusBadRequest, code: "request.invalid_parameter",
+	},
+}
+
+func TestRoutes(t *testing.T) {
+	for _, c := range routeCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newTestServer(t))
+		})
+	}
+}
+
+func TestRoutesWithMemoryRepositories(t *testing.T) {
+	for _, c := range routeCases {
+		if c.database {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newMemoryTestServer(t))
+		})
+	}
+}
+
+// TestRoutesCovered fails when a route of the router has no case.
+func TestRoutesCovered(t *testing.T) {
+	ts := newTestServer(t)
+	covered := map[string]bool{}
+	for _, c := range routeCases {
+		req := httptest.NewRequest(c.method, ts.expand(c.path), nil)
+		match := mux.RouteMatch{}
+		if !ts.server.Router.Match(req, &match) || match.Route == nil {
+			t.Errorf("case %q matches no route", c.name)
+			continue
+		}
+		template, _ := match.Route.GetPathTemplate()
+		covered[c.method+" "+template] = true
+	}
+
+	for _, route := range sorted(routes(t, ts.server.Router)) {
+		if !covered[route] {
+			t.Errorf("route %s has no test case", route)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ************************* Refresh Token
+func (server *Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
+		return
+	}
+	request := refreshRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
+		return
+	}
+	if request.RefreshToken == "" {
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrValidation.WithFields(exception.FieldError{
+			Field:   "refresh_token",
+			Rule:    "required",
+			Message: "Required Refresh Token",
+		}))
+		return
+	}
+
+	tokens, err := server.tokens.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		responses.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	responses.ResponseJSON(w, http.StatusOK, tokens)
+}
+
+// ************************* Logout
+// Revokes the access token used for the request and the refresh token in the
+// body, or every refresh token of the user when none is given.
+func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		responses.Error(w, r, http.StatusUnauthorized, exception.ErrUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
+		return
+	}
+	request := refreshRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
+			return
+		}
+	}
+
+	err = server.tokens.Logout(r.Context(), principal, request.RefreshToken)
+	if err != nil {
+		responses.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	responses.ResponseJSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,72 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+func (ts *testServer) login(email string) responses.Tokens {
+	ts.t.Helper()
+	rec := ts.do("POST", "/v1/login", "", body{"email": email, "password": password})
+	if rec.Code != http.StatusOK {
+		ts.t.Fatalf("login %s: %d %s", email, rec.Code, rec.Body.String())
+	}
+	tokens := responses.Tokens{}
+	decode(ts.t, rec, &tokens)
+	return tokens
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		first := ts.login("alice@mail.com")
+
+		rec := ts.do("POST", "/v1/token/refresh", "", body{"refresh_token": first.RefreshToken})
+		assert.Equal(t, rec.Code, http.StatusOK)
+		second := responses.Tokens{}
+		decode(t, rec, &second)
+		assert.NotEqual(t, second.RefreshToken, first.RefreshToken)
+		assert.Equal(t, ts.do("GET", "/v1/users", second.AccessToken, nil).Code, http.StatusOK)
+
+		// Presenting a rotated token again revokes the whole family
+		rec = ts.do("POST", "/v1/token/refresh", "", body{"refresh_token": first.RefreshToken})
+		assert.Equal(t, rec.Code, http.StatusUnauthorized)
+		assert.Equal(t, problem(t, rec).Code, "auth.refresh_token_reused")
+
+		rec = ts.do("POST", "/v1/token/refresh", "", body{"refresh_token": second.RefreshToken})
+		assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	})
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	ts := newTestServer(t)
+	tokens := ts.login("alice@mail.com")
+	other := ts.login("alice@mail.com")
+
+	rec := ts.do("POST", "/v1/logout", tokens.AccessToken, body{"refresh_token": tokens.RefreshToken})
+	assert.Equal(t, rec.Code, http.StatusNoContent)
+
+	rec = ts.do("PUT", ts.expand("/v1/users/{alice}"), tokens.AccessToken, newUser("alicia"))
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	rec = ts.do("POST", "/v1/token/refresh", "", body{"refresh_token": tokens.RefreshToken})
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+
+	// Only the family of the given refresh token is revoked
+	rec = ts.do("POST", "/v1/token/refresh", "", body{"refresh_token": other.RefreshToken})
+	assert.Equal(t, rec.Code, http.StatusOK)
+}
+
+func TestLoginAfterPasswordChange(t *testing.T) {
+	ts := newTestServer(t)
+	rec := ts.do("PUT", ts.expand("/v1/users/{alice}"), ts.token("alice"),
+		body{"nickname": "alice", "email": "alice@mail.com", "password": "changedpassword2"})
+	assert.Equal(t, rec.Code, http.StatusOK)
+
+	rec = ts.do("POST", "/v1/login", "", body{"email": "alice@mail.com", "password": password})
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	rec = ts.do("POST", "/v1/login", "", body{"email": "alice@mail.com", "password": "changedpassword2"})
+	assert.Equal(t, rec.Code, http.StatusOK)
+}
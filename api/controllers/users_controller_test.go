@@ -0,0 +1,16 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestUpdateUserRoleValidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ts *testServer) {
+		rec := ts.do("PUT", ts.expand("/v1/users/{alice}/role"), ts.token("dave"), body{"role": "owner"})
+		assert.Equal(t, rec.Code, http.StatusUnprocessableEntity)
+		assert.Equal(t, problem(t, rec).Code, "validation.failed")
+	})
+}
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -19,44 +18,46 @@ import (
 func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
+		return
 	}
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
-		return
-	}
-
-	user.Prepare()
-	err = user.Validate("")
-	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 
-	userCreated, err := user.SaveUser(server.DB)
-
+	userCreated, err := server.users.Register(r.Context(), user)
 	if err != nil {
-		formattedError := exception.FormatError(err.Error())
-
-		responses.Error(w, http.StatusUnprocessableEntity, formattedError)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, userCreated.ID))
-	responses.ResponseJSON(w, http.StatusCreated, userCreated)
+	responses.ResponseJSON(w, http.StatusCreated, responses.NewPrivateUser(userCreated))
 }
 
 // ---------------------- Get all users
 func (server *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
-	user := models.User{}
-	users, err := user.FindAllUsers(server.DB)
+	page, err := parsePagination(r)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, http.StatusBadRequest, err)
 		return
 	}
-	responses.ResponseJSON(w, http.StatusOK, users)
+
+	users, info, err := server.users.List(r.Context(), page)
+	if err != nil {
+		responses.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	responses.ResponseJSON(w, http.StatusOK, responses.Page{
+		Data:       responses.NewUserViews(users, viewerID(r)),
+		Total:      info.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: info.NextCursor,
+	})
 }
 
 // -------------------------- Get user by ID
@@ -64,17 +65,16 @@ func (server *Server) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		responses.ResponseJSON(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
 
-	user := models.User{}
-	getUser, err := user.FindUserById(server.DB, uint32(uid))
+	getUser, err := server.users.Get(r.Context(), uint32(uid))
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	responses.ResponseJSON(w, http.StatusOK, getUser)
+	responses.ResponseJSON(w, http.StatusOK, responses.NewUserView(getUser, viewerID(r)))
 }
 
 // ------------------------- Update User
@@ -82,72 +82,86 @@ func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
-	tokenID, err := auth.ExtractTokenID(r)
+	updatedUser, err := server.users.Update(r.Context(), actor(r), uint32(uid), user)
 	if err != nil {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
+	responses.ResponseJSON(w, http.StatusOK, responses.NewPrivateUser(updatedUser))
+}
 
-	if tokenID != uint32(uid) {
-		responses.Error(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
-		return
-	}
-	user.Prepare()
-	err = user.Validate("update")
+// -------------------------- Delete User
+func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		responses.Error(w, http.StatusUnprocessableEntity, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
-	updatedUser, err := user.UpdateUser(server.DB, uint32(uid))
+
+	err = server.users.Delete(r.Context(), actor(r), uint32(uid))
 	if err != nil {
-		formattedError := exception.FormatError(err.Error())
-		responses.Error(w, http.StatusInternalServerError, formattedError)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	responses.ResponseJSON(w, http.StatusOK, updatedUser)
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", uid))
+	responses.ResponseJSON(w, http.StatusNoContent, "")
+
 }
 
-// -------------------------- Delete User
-func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// ------------------------- Update User Role
+func (server *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	user := models.User{}
 	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err)
+		responses.Error(w, r, http.StatusBadRequest, exception.InvalidParameter("id", "integer", "Invalid id"))
 		return
 	}
-
-	tokenID, err := auth.ExtractTokenID(r)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
-
-	if tokenID != 0 && tokenID != uint32(uid) {
-		responses.Error(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	request := struct {
+		Role string `json:"role"`
+	}{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Error(w, r, http.StatusUnprocessableEntity, exception.ErrInvalidBody.Wrap(err))
 		return
 	}
-	_, err = user.DeleteUser(server.DB, uint32(uid))
+
+	updatedUser, err := server.users.UpdateRole(r.Context(), actor(r), uint32(uid), request.Role)
 	if err != nil {
-		responses.Error(w, http.StatusInternalServerError, err)
+		responses.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
+	responses.ResponseJSON(w, http.StatusOK, responses.NewPrivateUser(updatedUser))
+}
 
-	w.Header().Set("Entity", fmt.Sprintf("%d", uid))
-	responses.ResponseJSON(w, http.StatusNoContent, "")
+// actor returns the authenticated caller, or nil for anonymous requests
+func actor(r *http.Request) *auth.Principal {
+	p, _ := auth.PrincipalFromContext(r.Context())
+	return p
+}
 
+// viewerID returns the id of the authenticated caller, or 0 for anonymous requests
+func viewerID(r *http.Request) uint32 {
+	uid, _ := auth.UserIDFromContext(r.Context())
+	return uid
 }
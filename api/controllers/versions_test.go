@@ -0,0 +1,95 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+func TestUnversionedAliases(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	ts := newTestServerWith(t, config.API{Aliases: true, AliasDeprecation: since, AliasSunset: sunset})
+
+	rec := ts.do("GET", "/users?limit=1", "", nil)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Header().Get("Deprecation"), fmt.Sprintf("@%d", since.Unix()))
+	assert.Equal(t, rec.Header().Get("Sunset"), "Wed, 01 Jul 2026 00:00:00 GMT")
+	assert.Equal(t, rec.Header().Get("Link"), `</v1/users?limit=1>; rel="successor-version"`)
+
+	// The alias serves the same v1 handler
+	aliased := responses.Page{}
+	decode(t, rec, &aliased)
+	versioned := responses.Page{}
+	rec = ts.do("GET", "/v1/users?limit=1", "", nil)
+	decode(t, rec, &versioned)
+	assert.Equal(t, aliased, versioned)
+	assert.Equal(t, rec.Header().Get("Deprecation"), "")
+	assert.Equal(t, rec.Header().Get("Sunset"), "")
+
+	rec = ts.do("POST", "/login", "", body{"email": "alice@mail.com", "password": password})
+	assert.Equal(t, rec.Code, http.StatusOK)
+
+	// Errors of the aliases are announced the same way
+	rec = ts.do("DELETE", ts.expand("/posts/{alice_post}"), ts.token("bob"), nil)
+	assert.Equal(t, rec.Code, http.StatusForbidden)
+	assert.NotEqual(t, rec.Header().Get("Deprecation"), "")
+
+	// The probes are not versioned
+	rec = ts.do("GET", "/healthz", "", nil)
+	assert.Equal(t, rec.Header().Get("Deprecation"), "")
+	assert.Equal(t, rec.Header().Get("Link"), "")
+}
+
+func TestUnversionedAliasesNotDeprecated(t *testing.T) {
+	ts := newTestServerWith(t, config.API{Aliases: true})
+
+	rec := ts.do("GET", ts.expand("/posts/{alice_post}"), "", nil)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Header().Get("Deprecation"), "")
+	assert.Equal(t, rec.Header().Get("Sunset"), "")
+	assert.Equal(t, rec.Header().Get("Link"), fmt.Sprintf(`</v1/posts/%d>; rel="successor-version"`, ts.posts["alice"].ID))
+}
+
+func TestUnversionedAliasesDisabled(t *testing.T) {
+	ts := newTestServer(t)
+	assert.Equal(t, ts.do("GET", "/users", "", nil).Code, http.StatusNotFound)
+	assert.Equal(t, ts.do("GET", "/posts", "", nil).Code, http.StatusNotFound)
+	assert.Equal(t, ts.do("GET", "/v1/posts", "", nil).Code, http.StatusOK)
+}
+
+func TestAPIVersionNextToV1(t *testing.T) {
+	ts := newTestServerWith(t, config.API{Aliases: true})
+
+	// A v2 registered once the server is set up, v1 being deprecated by it
+	ts.server.APIVersion("v2").HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		responses.ResponseJSON(w, http.StatusOK, []string{"v2"})
+	}).Methods("GET")
+	ts.server.APIVersion("v1").Use(middlewares.Deprecation{
+		Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Successor: func(r *http.Request) string {
+			return "/v2" + r.URL.Path[len("/v1"):]
+		},
+	}.Handler)
+
+	rec := ts.do("GET", "/v2/users", "", nil)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Body.String(), "[\"v2\"]\n")
+	assert.Equal(t, rec.Header().Get("Deprecation"), "")
+
+	rec = ts.do("GET", "/v1/users", "", nil)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.NotEqual(t, rec.Header().Get("Deprecation"), "")
+	assert.Equal(t, rec.Header().Get("Link"), `</v2/users>; rel="successor-version"`)
+
+	// Routes v2 does not have yet are only served by v1
+	assert.Equal(t, ts.do("GET", "/v2/posts", "", nil).Code, http.StatusNotFound)
+	assert.Equal(t, ts.do("GET", "/users", "", nil).Code, http.StatusOK)
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+// Open connects to the configured database.
+func Open(cfg config.Database) (*gorm.DB, error) {
+	var DbURL string
+	dialect := cfg.Driver
+
+	switch cfg.Driver {
+	case "mysql":
+		DbURL = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+	case "postgres":
+		DbURL = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", cfg.Host, cfg.Port, cfg.User, cfg.Name, cfg.Password)
+	case "sqlite", "sqlite3":
+		// Name is a file path or :memory:, foreign keys are off by default in SQLite
+		dialect = "sqlite3"
+		DbURL = cfg.Name + "?_foreign_keys=1&_busy_timeout=5000"
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialect, DbURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %s database: %v", cfg.Driver, err)
+	}
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
+	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if dialect == "sqlite3" {
+		// Every connection to :memory: opens a new empty database, and SQLite
+		// only has one writer anyway
+		db.DB().SetMaxOpenConns(1)
+		db.DB().SetMaxIdleConns(1)
+		db.DB().SetConnMaxLifetime(0)
+	}
+	return db, nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const metricsStartKey = "metrics:start"
+
+// RegisterMetrics times every gorm operation through callbacks and exports
+// the connection pool statistics.
+func RegisterMetrics(db *gorm.DB, reg prometheus.Registerer) {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database operation latency, by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+	errors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Failed database operations, by operation and table. Record not found is not an error.",
+	}, []string{"operation", "table"})
+	reg.MustRegister(duration, errors, collectors.NewDBStatsCollector(db.DB(), db.Dialect().GetName()))
+
+	before := func(scope *gorm.Scope) {
+		scope.Set(metricsStartKey, time.Now())
+	}
+	after := func(operation string) func(scope *gorm.Scope) {
+		return func(scope *gorm.Scope) {
+			value, ok := scope.Get(metricsStartKey)
+			if !ok {
+				return
+			}
+			table := scope.TableName()
+			duration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+			if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+				errors.WithLabelValues(operation, table).Inc()
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().Before("gorm:begin_transaction").Register("metrics:before_create", before)
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("metrics:after_create", after("create"))
+	callbacks.Query().Before("gorm:query").Register("metrics:before_query", before)
+	callbacks.Query().After("gorm:after_query").Register("metrics:after_query", after("query"))
+	callbacks.Update().Before("gorm:begin_transaction").Register("metrics:before_update", before)
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("metrics:after_update", after("update"))
+	callbacks.Delete().Before("gorm:begin_transaction").Register("metrics:before_delete", before)
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("metrics:after_delete", after("delete"))
+	callbacks.RowQuery().Before("gorm:row_query").Register("metrics:before_row_query", before)
+	callbacks.RowQuery().After("gorm:row_query").Register("metrics:after_row_query", after("row_query"))
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/antonio91capa/go-apirest/api/logging"
+	"github.com/antonio91capa/go-apirest/api/tracing"
+)
+
+const (
+	tracingContextKey = "tracing:context"
+	tracingSpanKey    = "tracing:span"
+)
+
+// WithContext returns db carrying ctx, so the operations run through it are
+// traced as children of the span in ctx and logged with its request id.
+func WithContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return logging.WithGormContext(db.Set(tracingContextKey, ctx), ctx)
+}
+
+// RegisterTracing starts a client span around every gorm operation run
+// through a db returned by WithContext.
+func RegisterTracing(db *gorm.DB) {
+	system := db.Dialect().GetName()
+
+	before := func(operation string) func(scope *gorm.Scope) {
+		return func(scope *gorm.Scope) {
+			value, ok := scope.Get(tracingContextKey)
+			if !ok {
+				return
+			}
+			ctx, _ := value.(context.Context)
+			if ctx == nil {
+				return
+			}
+			table := scope.TableName()
+			_, span := tracing.Tracer().Start(ctx, operation+" "+table,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemKey.String(system),
+					semconv.DBOperation(operation),
+					semconv.DBSQLTable(table),
+				),
+			)
+			scope.Set(tracingSpanKey, span)
+		}
+	}
+	after := func(scope *gorm.Scope) {
+		value, ok := scope.Get(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		// The statement keeps its placeholders, the values are never recorded
+		if scope.SQL != "" {
+			span.SetAttributes(semconv.DBStatement(scope.SQL))
+		}
+		if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().Before("gorm:begin_transaction").Register("tracing:before_create", before("create"))
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("tracing:after_create", after)
+	callbacks.Query().Before("gorm:query").Register("tracing:before_query", before("query"))
+	callbacks.Query().After("gorm:after_query").Register("tracing:after_query", after)
+	callbacks.Update().Before("gorm:begin_transaction").Register("tracing:before_update", before("update"))
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("tracing:after_update", after)
+	callbacks.Delete().Before("gorm:begin_transaction").Register("tracing:before_delete", before("delete"))
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("tracing:after_delete", after)
+	callbacks.RowQuery().Before("gorm:row_query").Register("tracing:before_row_query", before("row_query"))
+	callbacks.RowQuery().After("gorm:row_query").Register("tracing:after_row_query", after)
+}
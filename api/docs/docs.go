@@ -0,0 +1,86 @@
+// Package docs embeds the OpenAPI document of the API, maintained by hand in
+// openapi.json next to routes.go, and the Swagger UI page rendering it. The
+// Swagger UI dist files are vendored in ui (see ui/NOTICE-swagger-ui) and
+// served from the binary, no script is loaded from elsewhere.
+package docs
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed ui
+var ui embed.FS
+
+// aliasServer is the server of the unversioned aliases in openapi.json
+const aliasServer = "/"
+
+var (
+	withoutAliasesOnce sync.Once
+	withoutAliases     []byte
+)
+
+// OpenAPI returns the OpenAPI 3 document. The unversioned aliases are listed
+// among its servers only when they are served.
+func OpenAPI(aliases bool) []byte {
+	if aliases {
+		return spec
+	}
+	withoutAliasesOnce.Do(func() {
+		withoutAliases = dropServer(spec, aliasServer)
+	})
+	return withoutAliases
+}
+
+// dropServer removes the top-level server url of the document, the servers of
+// the path items are left as they are.
+func dropServer(document []byte, url string) []byte {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		panic("docs: openapi.json: " + err.Error())
+	}
+	servers := []map[string]interface{}{}
+	if err := json.Unmarshal(doc["servers"], &servers); err != nil {
+		panic("docs: openapi.json servers: " + err.Error())
+	}
+	kept := servers[:0]
+	for _, server := range servers {
+		if server["url"] != url {
+			kept = append(kept, server)
+		}
+	}
+
+	var err error
+	if doc["servers"], err = json.Marshal(kept); err != nil {
+		panic(err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// UI returns the HTML page of Swagger UI, which loads the document from /openapi.json.
+func UI() []byte {
+	page, _ := Asset("index.html")
+	return page
+}
+
+// Asset returns the file name of ui, such as swagger-ui-bundle.js.
+func Asset(name string) ([]byte, bool) {
+	if !fs.ValidPath(name) || path.Dir(name) != "." {
+		return nil, false
+	}
+	b, err := ui.ReadFile("ui/" + name)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
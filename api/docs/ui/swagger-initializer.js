@@ -0,0 +1,11 @@
+// Renders /openapi.json with the vendored Swagger UI. Kept out of index.html
+// so the page needs no inline script.
+window.onload = function () {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl]
+  });
+};
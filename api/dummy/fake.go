@@ -0,0 +1,39 @@
+package dummy
+
+import "fmt"
+
+// FakePassword is the password of every generated user.
+const FakePassword = "fakepassw0rd"
+
+// Fake generates users and posts for load testing. Names are numbered, so
+// seeding the same amounts again updates nothing, and posts are spread over
+// the generated users.
+func Fake(users, posts int) (*Fixtures, error) {
+	if users < 0 || posts < 0 {
+		return nil, fmt.Errorf("fake users and posts must not be negative")
+	}
+	if posts > 0 && users == 0 {
+		return nil, fmt.Errorf("fake posts need at least one fake user as author")
+	}
+
+	f := &Fixtures{
+		Users: make([]UserFixture, users),
+		Posts: make([]PostFixture, posts),
+	}
+	for i := range f.Users {
+		nickname := fmt.Sprintf("fake-user-%06d", i+1)
+		f.Users[i] = UserFixture{
+			Nickname: nickname,
+			Email:    nickname + "@example.com",
+			Password: FakePassword,
+		}
+	}
+	for i := range f.Posts {
+		f.Posts[i] = PostFixture{
+			Title:   fmt.Sprintf("Fake post %06d", i+1),
+			Content: fmt.Sprintf("Generated content of fake post number %d", i+1),
+			Author:  f.Users[i%users].Nickname,
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,268 @@
+package dummy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures are the users and posts to seed, read from a YAML or JSON file.
+type Fixtures struct {
+	Users []UserFixture `json:"users" yaml:"users"`
+	Posts []PostFixture `json:"posts" yaml:"posts"`
+}
+
+type UserFixture struct {
+	Nickname string `json:"nickname" yaml:"nickname"`
+	Email    string `json:"email" yaml:"email"`
+	Password string `json:"password" yaml:"password"`
+	Role     string `json:"role,omitempty" yaml:"role,omitempty"`
+}
+
+// PostFixture references its author by nickname.
+type PostFixture struct {
+	Title   string `json:"title" yaml:"title"`
+	Content string `json:"content" yaml:"content"`
+	Author  string `json:"author" yaml:"author"`
+}
+
+// Result counts the rows touched by Apply.
+type Result struct {
+	UsersCreated, UsersUpdated int
+	PostsCreated, PostsUpdated int
+}
+
+// userRow writes users with passwords hashed once per distinct value instead
+// of once per row, so the users seeded together share their hash.
+type userRow struct {
+	ID        uint32
+	Nickname  string
+	Email     string
+	Password  string
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (userRow) TableName() string {
+	return "users"
+}
+
+// LoadFile reads fixtures from a .yaml, .yml or .json file.
+func LoadFile(path string) (*Fixtures, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixtures{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, f)
+	case ".json":
+		err = json.Unmarshal(data, f)
+	default:
+		return nil, fmt.Errorf("%s: unsupported fixture format, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Merge appends the users and posts of other.
+func (f *Fixtures) Merge(other *Fixtures) {
+	f.Users = append(f.Users, other.Users...)
+	f.Posts = append(f.Posts, other.Posts...)
+}
+
+// Apply upserts the fixtures in one transaction. Users are matched by nickname
+// and posts by title, so applying the same fixtures again changes nothing.
+func (f *Fixtures) Apply(db *gorm.DB) (Result, error) {
+	result := Result{}
+	tx := db.Begin()
+	if tx.Error != nil {
+		return result, tx.Error
+	}
+
+	authors, err := f.applyUsers(tx, &result)
+	if err == nil {
+		err = f.applyPosts(tx, authors, &result)
+	}
+	if err != nil {
+		tx.Rollback()
+		return Result{}, err
+	}
+	return result, tx.Commit().Error
+}
+
+// ****** Users
+func (f *Fixtures) applyUsers(tx *gorm.DB, result *Result) (map[string]uint32, error) {
+	authors := map[string]uint32{}
+	hashes := map[string]string{}
+	hash := func(password string) (string, error) {
+		if hashed, ok := hashes[password]; ok {
+			return hashed, nil
+		}
+		b, err := models.Hash(password)
+		if err != nil {
+			return "", err
+		}
+		hashes[password] = string(b)
+		return string(b), nil
+	}
+	// matches caches the checks of the stored hashes against the fixture
+	// passwords, one bcrypt comparison covers every user sharing a hash
+	matches := map[[2]string]bool{}
+
+	for i, fixture := range f.Users {
+		user := models.User{Nickname: fixture.Nickname, Email: fixture.Email, Password: fixture.Password}
+		user.Prepare()
+		if fixture.Role != "" {
+			user.Role = fixture.Role
+		}
+		if err := user.ValidateCreate(); err != nil {
+			return nil, fmt.Errorf("user #%d %q: %v", i+1, fixture.Nickname, describe(err))
+		}
+		if !models.ValidRole(user.Role) {
+			return nil, fmt.Errorf("user #%d %q: invalid role %q", i+1, fixture.Nickname, user.Role)
+		}
+
+		existing := userRow{}
+		err := tx.Where("nickname = ?", user.Nickname).Take(&existing).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+
+		if err == nil {
+			changes := map[string]interface{}{}
+			if existing.Email != user.Email {
+				changes["email"] = user.Email
+			}
+			if existing.Role != user.Role {
+				changes["role"] = user.Role
+			}
+			key := [2]string{existing.Password, user.Password}
+			match, checked := matches[key]
+			if !checked {
+				match = models.VerifyPassword(existing.Password, user.Password) == nil
+				matches[key] = match
+			}
+			if !match {
+				hashed, err := hash(user.Password)
+				if err != nil {
+					return nil, err
+				}
+				changes["password"] = hashed
+			}
+			if len(changes) > 0 {
+				changes["updated_at"] = time.Now()
+				err = tx.Model(&userRow{}).Where("id = ?", existing.ID).UpdateColumns(changes).Error
+				if err != nil {
+					return nil, fmt.Errorf("user %q: %v", user.Nickname, err)
+				}
+				result.UsersUpdated++
+			}
+			authors[user.Nickname] = existing.ID
+			continue
+		}
+
+		hashed, err := hash(user.Password)
+		if err != nil {
+			return nil, err
+		}
+		row := userRow{
+			Nickname:  user.Nickname,
+			Email:     user.Email,
+			Password:  hashed,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		}
+		if err = tx.Create(&row).Error; err != nil {
+			return nil, fmt.Errorf("user %q: %v", user.Nickname, err)
+		}
+		authors[user.Nickname] = row.ID
+		result.UsersCreated++
+	}
+	return authors, nil
+}
+
+// ****** Posts
+func (f *Fixtures) applyPosts(tx *gorm.DB, authors map[string]uint32, result *Result) error {
+	for i, fixture := range f.Posts {
+		nickname := html.EscapeString(strings.TrimSpace(fixture.Author))
+		authorID, ok := authors[nickname]
+		if !ok {
+			author := userRow{}
+			err := tx.Where("nickname = ?", nickname).Take(&author).Error
+			if gorm.IsRecordNotFoundError(err) {
+				return fmt.Errorf("post #%d %q: unknown author %q", i+1, fixture.Title, fixture.Author)
+			}
+			if err != nil {
+				return err
+			}
+			authorID = author.ID
+			authors[nickname] = authorID
+		}
+
+		post := models.Post{Title: fixture.Title, Content: fixture.Content, AuthorID: authorID}
+		post.Prepare()
+		if err := post.Validate(); err != nil {
+			return fmt.Errorf("post #%d %q: %v", i+1, fixture.Title, describe(err))
+		}
+
+		existing := models.Post{}
+		err := tx.Where("title = ?", post.Title).Take(&existing).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err == nil {
+			if existing.Content == post.Content && existing.AuthorID == post.AuthorID {
+				continue
+			}
+			err = tx.Model(&models.Post{}).Where("id = ?", existing.ID).UpdateColumns(
+				map[string]interface{}{
+					"content":    post.Content,
+					"author_id":  post.AuthorID,
+					"updated_at": time.Now(),
+				},
+			).Error
+			if err != nil {
+				return fmt.Errorf("post %q: %v", post.Title, err)
+			}
+			result.PostsUpdated++
+			continue
+		}
+
+		if err = tx.Create(&post).Error; err != nil {
+			return fmt.Errorf("post %q: %v", post.Title, err)
+		}
+		result.PostsCreated++
+	}
+	return nil
+}
+
+// describe lists the field errors of a validation failure.
+func describe(err error) string {
+	var ve *exception.Error
+	if !errors.As(err, &ve) || len(ve.Fields) == 0 {
+		return err.Error()
+	}
+	messages := make([]string, len(ve.Fields))
+	for i, field := range ve.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,77 @@
+package exception
+
+// Kind classifies errors independently of the transport used to report them.
+type Kind int
+
+const (
+	Internal Kind = iota
+	Malformed
+	Invalid
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+)
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a stable, machine readable code such as
+// "user.email_taken". Two errors with the same code match with errors.Is.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithFields returns a copy of e carrying per-field details.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	copied := *e
+	copied.Fields = append(append([]FieldError{}, e.Fields...), fields...)
+	return &copied
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+var (
+	ErrUnauthorized     = New(Unauthorized, "auth.unauthorized", "Unauthorized")
+	ErrForbidden        = New(Forbidden, "auth.forbidden", "Forbidden")
+	ErrNotFound         = New(NotFound, "resource.not_found", "Not Found")
+	ErrInvalidParameter = New(Malformed, "request.invalid_parameter", "Invalid parameter")
+	ErrInvalidBody      = New(Invalid, "request.invalid_body", "Invalid request body")
+	ErrValidation       = New(Invalid, "validation.failed", "Validation failed")
+	ErrInternal         = New(Internal, "internal", "Internal Server Error")
+)
+
+// InvalidParameter reports a query or path parameter that could not be parsed.
+func InvalidParameter(name, rule, message string) *Error {
+	return ErrInvalidParameter.WithFields(FieldError{Field: name, Rule: rule, Message: message})
+}
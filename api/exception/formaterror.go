@@ -1,26 +1,99 @@
 package exception
 
 import (
-	"errors"
+	"regexp"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
-func FormatError(err string) error {
-	if strings.Contains(err, "nickname") {
-		return errors.New("Nickname Already taken")
-	}
+var (
+	mysqlDuplicateKey = regexp.MustCompile(`for key '([^']+)'`)
+	sqliteUniqueKey   = regexp.MustCompile(`UNIQUE constraint failed: ([\w.]+)`)
+	mysqlForeignKey   = regexp.MustCompile("FOREIGN KEY \\(`([^`]+)`\\)")
+	postgresKey       = regexp.MustCompile(`Key \(([^)]+)\)`)
+)
 
-	if strings.Contains(err, "email") {
-		return errors.New("Email already taken")
+// UniqueViolation tells whether err is a unique constraint violation and on
+// which column, using the error types of the database drivers.
+func UniqueViolation(err error) (string, bool) {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		// Error 1062: Duplicate entry 'x' for key 'users.nickname'
+		if e.Number != 1062 {
+			return "", false
+		}
+		match := mysqlDuplicateKey.FindStringSubmatch(e.Message)
+		if match == nil {
+			return "", true
+		}
+		return columnName(match[1]), true
+	case *pq.Error:
+		// 23505 unique_violation on constraint users_nickname_key
+		if e.Code != "23505" {
+			return "", false
+		}
+		constraint := strings.TrimSuffix(e.Constraint, "_key")
+		if e.Table != "" {
+			constraint = strings.TrimPrefix(constraint, e.Table+"_")
+		}
+		return constraint, true
+	case pq.Error:
+		return UniqueViolation(&e)
+	case sqlite3.Error:
+		// UNIQUE constraint failed: users.nickname
+		if e.ExtendedCode != sqlite3.ErrConstraintUnique {
+			return "", false
+		}
+		match := sqliteUniqueKey.FindStringSubmatch(e.Error())
+		if match == nil {
+			return "", true
+		}
+		return columnName(match[1]), true
 	}
+	return "", false
+}
 
-	if strings.Contains(err, "title") {
-		return errors.New("Title already taken")
+// ForeignKeyViolation tells whether err is a foreign key violation and on
+// which column. SQLite does not report the column, it is empty then.
+func ForeignKeyViolation(err error) (string, bool) {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		// Error 1452: Cannot add or update a child row: a foreign key constraint
+		// fails (... FOREIGN KEY (`author_id`) REFERENCES `users` (`id`) ...)
+		if e.Number != 1452 {
+			return "", false
+		}
+		match := mysqlForeignKey.FindStringSubmatch(e.Message)
+		if match == nil {
+			return "", true
+		}
+		return match[1], true
+	case *pq.Error:
+		// 23503 foreign_key_violation, detail Key (author_id)=(7) is not present in table "users".
+		if e.Code != "23503" {
+			return "", false
+		}
+		match := postgresKey.FindStringSubmatch(e.Detail)
+		if match == nil {
+			return "", true
+		}
+		return match[1], true
+	case pq.Error:
+		return ForeignKeyViolation(&e)
+	case sqlite3.Error:
+		// FOREIGN KEY constraint failed
+		return "", e.ExtendedCode == sqlite3.ErrConstraintForeignKey
 	}
+	return "", false
+}
 
-	if strings.Contains(err, "hashedPassword") {
-		return errors.New("Incorrect Password")
+// columnName strips the table prefix MySQL 8 and SQLite add to key names
+func columnName(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[i+1:]
 	}
-
-	return errors.New("Incorrect Details")
+	return key
 }
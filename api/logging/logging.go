@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/antonio91capa/go-apirest/api/config"
+)
+
+// ParseLevel reads debug, info, warn or error.
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+// New returns a logger writing cfg.Format (json or text) records to w.
+func New(cfg config.Log, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		return slog.New(NewContextHandler(slog.NewJSONHandler(w, opts))), nil
+	case "text":
+		return slog.New(NewContextHandler(slog.NewTextHandler(w, opts))), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+}
+
+// contextHandler adds the request id and the trace of the context to the
+// records logged with one, e.g. through slog.ErrorContext.
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h so every record logged with the context of a
+// request carries its request_id, trace_id and span_id.
+func NewContextHandler(h slog.Handler) slog.Handler {
+	return contextHandler{Handler: h}
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := []slog.Attr{}
+	if id := RequestID(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	// The access log sets them itself
+	present := map[string]bool{}
+	record.Attrs(func(attr slog.Attr) bool {
+		present[attr.Key] = true
+		return true
+	})
+	record = record.Clone()
+	for _, attr := range attrs {
+		if !present[attr.Key] {
+			record.AddAttrs(attr)
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the request being served, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// gormLoggerKey holds the gormLogger of a db, bound to a context by WithGormContext.
+const gormLoggerKey = "logging:gorm_logger"
+
+// gormLogger sends the gorm log lines to slog at debug level, with the
+// context of the request running the queries.
+type gormLogger struct {
+	logger *slog.Logger
+	ctx    context.Context
+}
+
+func (l gormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		return
+	}
+	source := fmt.Sprint(values[1])
+
+	if values[0] == "sql" && len(values) >= 6 {
+		duration, _ := values[2].(time.Duration)
+		l.logger.DebugContext(l.ctx, "sql",
+			"source", source,
+			"sql", values[3],
+			"vars", values[4],
+			"rows", values[5],
+			"duration_ms", float64(duration.Microseconds())/1000,
+		)
+		return
+	}
+	l.logger.DebugContext(l.ctx, fmt.Sprint(values[2:]...), "source", source, "kind", values[0])
+}
+
+// UseWithGorm logs the queries of db through logger, only when its level is debug.
+func UseWithGorm(db *gorm.DB, logger *slog.Logger) {
+	l := gormLogger{logger: logger.With("component", "gorm"), ctx: context.Background()}
+	db.SetLogger(l)
+	db.InstantSet(gormLoggerKey, l)
+	db.LogMode(logger.Enabled(context.Background(), slog.LevelDebug))
+}
+
+// WithGormContext returns db logging its statements with ctx, so they carry
+// the request id. db is returned as is when UseWithGorm was not called on it.
+func WithGormContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	value, ok := db.Get(gormLoggerKey)
+	l, isLogger := value.(gormLogger)
+	if !ok || !isLogger {
+		return db
+	}
+	l.ctx = ctx
+	db = db.Set(gormLoggerKey, l)
+	db.SetLogger(l)
+	return db
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecation announces a deprecated route to its clients with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) response headers.
+type Deprecation struct {
+	// Since is when the route was deprecated.
+	Since time.Time
+	// Sunset is when the route stops being served, not announced when zero.
+	Sunset time.Time
+	// Successor returns the URL replacing the one requested, announced in a
+	// Link header with rel="successor-version". Optional.
+	Successor func(r *http.Request) string
+}
+
+// Handler adds the headers to every response of next, it can be given to
+// mux.Router.Use to deprecate a whole version.
+func (d Deprecation) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.Since.IsZero() {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+		}
+		if !d.Sunset.IsZero() {
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != nil {
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor(r)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,10 +1,10 @@
 package middlewares
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/exception"
 	"github.com/antonio91capa/go-apirest/api/responses"
 )
 
@@ -15,13 +15,31 @@ func SetMiddlewareJSON(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// SetMiddlewareAuthentication rejects requests without a valid access token and
+// stores the caller in the request context, see auth.PrincipalFromContext.
 func SetMiddlewareAuthentication(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := auth.TokenValid(r)
+		principal, err := auth.ParsePrincipal(r)
 		if err != nil {
-			responses.Error(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+			responses.Error(w, r, http.StatusUnauthorized, exception.ErrUnauthorized)
 			return
 		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// SetMiddlewareOptionalAuthentication stores the caller in the request context
+// when a valid access token is given, and lets anonymous requests through.
+func SetMiddlewareOptionalAuthentication(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.ExtractToken(r) != "" {
+			principal, err := auth.ParsePrincipal(r)
+			if err != nil {
+				responses.Error(w, r, http.StatusUnauthorized, exception.ErrUnauthorized)
+				return
+			}
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
 		next(w, r)
 	}
 }
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// Policy grants access to principals holding any of Roles, or owning the
+// resource. An empty policy only requires authentication. The services check
+// the policies of their operations with Allow, once they know the owner.
+type Policy struct {
+	Roles []string
+}
+
+var (
+	// ManageUser lets admins manage any user, users themselves
+	ManageUser = Policy{Roles: []string{models.RoleAdmin}}
+	// GrantRole lets only admins grant roles
+	GrantRole = Policy{Roles: []string{models.RoleAdmin}}
+	// EditPost lets moderators edit or delete any post, authors their own ones
+	EditPost = Policy{Roles: []string{models.RoleModerator, models.RoleAdmin}}
+)
+
+// Allow lets principal through when it owns the resource or holds one of the
+// roles. A nil principal is an anonymous caller.
+func (p Policy) Allow(principal *auth.Principal, owner bool) error {
+	if principal == nil {
+		return exception.ErrUnauthorized
+	}
+	if owner || len(p.Roles) == 0 {
+		return nil
+	}
+	for _, role := range p.Roles {
+		if principal.HasRole(role) {
+			return nil
+		}
+	}
+	return exception.ErrForbidden
+}
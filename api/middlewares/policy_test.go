@@ -0,0 +1,43 @@
+package middlewares_test
+
+import (
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+func TestPolicyAllow(t *testing.T) {
+	principal := func(role string) *auth.Principal {
+		return &auth.Principal{UserID: 1, Roles: []string{role}}
+	}
+	cases := []struct {
+		name      string
+		policy    middlewares.Policy
+		principal *auth.Principal
+		owner     bool
+		err       error
+	}{
+		{"anonymous", middlewares.EditPost, nil, false, exception.ErrUnauthorized},
+		{"anonymous owner", middlewares.EditPost, nil, true, exception.ErrUnauthorized},
+		{"author", middlewares.EditPost, principal(models.RoleUser), true, nil},
+		{"other user", middlewares.EditPost, principal(models.RoleUser), false, exception.ErrForbidden},
+		{"moderator edits any post", middlewares.EditPost, principal(models.RoleModerator), false, nil},
+		{"admin edits any post", middlewares.EditPost, principal(models.RoleAdmin), false, nil},
+		{"user manages itself", middlewares.ManageUser, principal(models.RoleUser), true, nil},
+		{"moderator manages other users", middlewares.ManageUser, principal(models.RoleModerator), false, exception.ErrForbidden},
+		{"admin manages any user", middlewares.ManageUser, principal(models.RoleAdmin), false, nil},
+		{"moderator grants roles", middlewares.GrantRole, principal(models.RoleModerator), false, exception.ErrForbidden},
+		{"admin grants roles", middlewares.GrantRole, principal(models.RoleAdmin), false, nil},
+		{"empty policy", middlewares.Policy{}, principal(models.RoleUser), false, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.policy.Allow(c.principal, c.owner), c.err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Schema snapshots, kept here so later model changes don't rewrite history.
+type user0001 struct {
+	ID        uint32    `gorm:"primary_key;auto_increment"`
+	Nickname  string    `gorm:"size:255;not null;unique"`
+	Email     string    `gorm:"size:100;not null;unique"`
+	Password  string    `gorm:"size:100;not null;"`
+	CreatedAt time.Time `gorm:"default:null"`
+	UpdatedAt time.Time `gorm:"default:null"`
+}
+
+func (user0001) TableName() string { return "users" }
+
+type post0001 struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	Title     string    `gorm:"size:255;not null;unique"`
+	Content   string    `gorm:"size:255;not null;"`
+	AuthorID  uint32    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"default:null"`
+	UpdatedAt time.Time `gorm:"default:null"`
+}
+
+func (post0001) TableName() string { return "posts" }
+
+// SQLite can't add foreign keys to existing tables, they are declared with the column.
+type sqlitePost0001 struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	Title     string    `gorm:"size:255;not null;unique"`
+	Content   string    `gorm:"size:255;not null;"`
+	AuthorID  uint32    `gorm:"type:integer REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE;not null"`
+	CreatedAt time.Time `gorm:"default:null"`
+	UpdatedAt time.Time `gorm:"default:null"`
+}
+
+func (sqlitePost0001) TableName() string { return "posts" }
+
+// Databases created by the old AutoMigrate already have these tables,
+// they are adopted as they are.
+var createUsersAndPosts = Migration{
+	Version: 1,
+	Name:    "create_users_and_posts",
+	Up: func(tx *gorm.DB) error {
+		if !tx.HasTable(&user0001{}) {
+			if err := tx.CreateTable(&user0001{}).Error; err != nil {
+				return err
+			}
+		}
+		if tx.HasTable(&post0001{}) {
+			return nil
+		}
+		if isSQLite(tx) {
+			return tx.CreateTable(&sqlitePost0001{}).Error
+		}
+		if err := tx.CreateTable(&post0001{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&post0001{}).AddForeignKey("author_id", "users(id)", "cascade", "cascade").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(&post0001{}, &user0001{}).Error
+	},
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type refreshToken0002 struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	FamilyID  string    `gorm:"size:32;not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"default:null"`
+}
+
+func (refreshToken0002) TableName() string { return "refresh_tokens" }
+
+type sqliteRefreshToken0002 struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"type:integer REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE;not null;index"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	FamilyID  string    `gorm:"size:32;not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"default:null"`
+}
+
+func (sqliteRefreshToken0002) TableName() string { return "refresh_tokens" }
+
+type revokedToken0002 struct {
+	JTI       string    `gorm:"primary_key;size:32"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (revokedToken0002) TableName() string { return "revoked_tokens" }
+
+var createTokenTables = Migration{
+	Version: 2,
+	Name:    "create_token_tables",
+	Up: func(tx *gorm.DB) error {
+		switch {
+		case tx.HasTable(&refreshToken0002{}):
+		case isSQLite(tx):
+			if err := tx.CreateTable(&sqliteRefreshToken0002{}).Error; err != nil {
+				return err
+			}
+		default:
+			err := tx.CreateTable(&refreshToken0002{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&refreshToken0002{}).AddForeignKey("user_id", "users(id)", "cascade", "cascade").Error
+			if err != nil {
+				return err
+			}
+		}
+		if tx.HasTable(&revokedToken0002{}) {
+			return nil
+		}
+		return tx.CreateTable(&revokedToken0002{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(&revokedToken0002{}, &refreshToken0002{}).Error
+	},
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "github.com/jinzhu/gorm"
+
+var addUserRole = Migration{
+	Version: 3,
+	Name:    "add_user_role",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialect().HasColumn("users", "role") {
+			return nil
+		}
+		return tx.Exec("ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user'").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("ALTER TABLE users DROP COLUMN role").Error
+	},
+}
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migration is a numbered, reversible schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// All migrations of the API, in the order they are applied.
+var All = []Migration{
+	createUsersAndPosts,
+	createTokenTables,
+	addUserRole,
+}
+
+var (
+	ErrNoMigrations   = errors.New("no applied migrations to roll back")
+	ErrNoVersionTable = errors.New("schema_migrations table missing, no migration applied")
+)
+
+// schemaMigration records an applied migration in the schema version table.
+type schemaMigration struct {
+	Version   int64     `gorm:"primary_key;auto_increment:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status of a known migration, AppliedAt is nil while it is pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns a Migrator for every migration in All.
+func New(db *gorm.DB) *Migrator {
+	return NewWith(db, All)
+}
+
+func NewWith(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	if m.db.HasTable(&schemaMigration{}) {
+		return nil
+	}
+	return m.db.CreateTable(&schemaMigration{}).Error
+}
+
+func (m *Migrator) applied() (map[int64]time.Time, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows := []schemaMigration{}
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// Status lists every known migration with the time it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+// ReadStatus is Status without any schema change, for the readiness probe:
+// it fails with ErrNoVersionTable until the first migration. The versions are
+// read within ctx.
+func (m *Migrator) ReadStatus(ctx context.Context) ([]Status, error) {
+	if !m.db.HasTable(&schemaMigration{}) {
+		return nil, ErrNoVersionTable
+	}
+	rows, err := m.db.DB().QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		row := schemaMigration{}
+		if err = rows.Scan(&row.Version, &row.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[row.Version] = row.AppliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+func (m *Migrator) status(applied map[int64]time.Time) []Status {
+	status := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		status[i].Migration = migration
+		if at, ok := applied[migration.Version]; ok {
+			status[i].AppliedAt = &at
+		}
+	}
+	return status
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration, each one in its own transaction.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, migration := range pending {
+		err = m.run(migration, migration.Up, func(tx *gorm.DB) error {
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down() (*Migration, error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(status) - 1; i >= 0; i-- {
+		if status[i].AppliedAt == nil {
+			continue
+		}
+		migration := status[i].Migration
+		if migration.Down == nil {
+			return nil, fmt.Errorf("migration %d_%s is irreversible", migration.Version, migration.Name)
+		}
+		err = m.run(migration, migration.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &migration, nil
+	}
+	return nil, ErrNoMigrations
+}
+
+func (m *Migrator) run(migration Migration, change, record func(tx *gorm.DB) error) error {
+	tx := m.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := change(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return tx.Commit().Error
+}
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialect().GetName() == "sqlite3"
+}
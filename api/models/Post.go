@@ -1,7 +1,6 @@
 package models
 
 import (
-	"errors"
 	"html"
 	"strings"
 	"time"
@@ -10,10 +9,10 @@ import (
 )
 
 type Post struct {
-	ID      uint64 `gorm:"primary_key;auto_increment" json:"id"`
-	Title   string `gorm:"size:255;not null;unique" json:"title"`
-	Content string `gorm:"size:255;not null;" json:"content"`
-	//Author    User      `json:"author"`
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	Title     string    `gorm:"size:255;not null;unique" json:"title"`
+	Content   string    `gorm:"size:255;not null;" json:"content"`
+	Author    *User     `gorm:"foreignkey:AuthorID;association_autoupdate:false;association_autocreate:false" json:"author,omitempty"`
 	AuthorID  uint32    `gorm:"not null" json:"author_id"`
 	CreatedAt time.Time `gorm:"default:null" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:null" json:"updated_at"`
@@ -23,77 +22,69 @@ func (p *Post) Prepare() {
 	p.ID = 0
 	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
 	p.Content = html.EscapeString(strings.TrimSpace(p.Content))
-	//p.Author = User{}
+	p.Author = nil
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 }
 
 func (p *Post) Validate() error {
-	if p.Title == "" {
-		return errors.New("Required Title")
+	v := Validator{}
+	if v.Required("title", p.Title) {
+		v.ColumnSize(p, "Title", "title", p.Title)
 	}
-	if p.Content == "" {
-		return errors.New("Required Content")
+	if v.Required("content", p.Content) {
+		v.ColumnSize(p, "Content", "content", p.Content)
 	}
-	if p.AuthorID < 1 {
-		return errors.New("Required Author")
-	}
-	return nil
+	v.Check(p.AuthorID > 0, "author_id", "required", "Required author_id")
+	return v.Err()
 }
 
 /* *********************** Save Posts *********************/
 func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Create(&p).Error
+	err = db.Model(&Post{}).Create(&p).Error
 	if err != nil {
-		return &Post{}, err
+		return &Post{}, postError(err)
 	}
-
-	//Muestra los detalles del user dentro del post
-	/*if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id=?", p.AuthorID).Take(&p.Author).Error
-		if err != nil {
-			return &Post{}, err
-		}
-	}*/
 	return p, nil
 }
 
 /********************* Find Posts by Author(User) *****************************/
-func (p *Post) FindAllPosts(db *gorm.DB) (*[]Post, error) {
+func (p *Post) FindAllPosts(db *gorm.DB, filter PostFilter, page Pagination) (*[]Post, *PageInfo, error) {
 	var err error
 	posts := []Post{}
-	err = db.Debug().Model(&Post{}).Limit(100).Find(&posts).Error
+	info := PageInfo{}
+	page.Normalize()
+	if filter.Sort.Field == "" {
+		filter.Sort = DefaultSort
+	}
+
+	err = filter.apply(db.Model(&Post{})).Count(&info.Total).Error
 	if err != nil {
-		return &[]Post{}, err
+		return &[]Post{}, &PageInfo{}, err
+	}
+	query, err := page.apply(filter.apply(db.Model(&Post{})), filter.Sort)
+	if err != nil {
+		return &[]Post{}, &PageInfo{}, err
+	}
+	err = query.Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, &PageInfo{}, err
+	}
+	if len(posts) > page.Limit {
+		posts = posts[:page.Limit]
+		last := posts[len(posts)-1]
+		info.NextCursor = NewCursor(filter.Sort, last.SortValue(filter.Sort.Field), last.ID).Encode()
 	}
-	/*if len(posts) > 0 {
-		for i, _ := range posts {
-			err := db.Debug().Model(&User{}).Where("id=?", posts[i].AuthorID).Take(&posts[i].Author).Error
-			if err != nil {
-				return &[]Post{}, err
-			}
-		}
-	}*/
-	return &posts, nil
+	return &posts, &info, nil
 }
 
 /******************* Find Post by ID ****************************/
 func (p *Post) FindPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id=?", pid).Take(&p).Error
+	err = db.Model(&Post{}).Where("id=?", pid).Take(&p).Error
 	if err != nil {
-		return &Post{}, err
-	}
-	/*if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id=?", p.AuthorID).Take(&p).Error
-		if err != nil {
-			return &Post{}, err
-		}
-	}*/
-
-	if gorm.IsRecordNotFoundError(err) {
-		return &Post{}, errors.New("Post not found")
+		return &Post{}, postError(err)
 	}
 	return p, nil
 }
@@ -101,28 +92,27 @@ func (p *Post) FindPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 /* **************************** Update Post *************************/
 func (p *Post) UpdatePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id=?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, UpdatedAt: time.Now()}).Error
+	err = db.Model(&Post{}).Where("id=?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, UpdatedAt: time.Now()}).Error
 	if err != nil {
-		return &Post{}, err
+		return &Post{}, postError(err)
 	}
-
-	/*if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id=?", p.AuthorID).Take(&p.Author).Error
-		if err != nil {
-			return &Post{}, err
-		}
-	}*/
 	return p, nil
 }
 
 /************************** Delete Post ****************************/
 func (p *Post) DeletePost(db *gorm.DB, pid uint64, uid uint32) (int64, error) {
-	db = db.Debug().Model(&Post{}).Where("id=? and author_id=?", pid, uid).Take(&Post{}).Delete(&Post{})
+	db = db.Model(&Post{}).Where("id=? and author_id=?", pid, uid).Take(&Post{}).Delete(&Post{})
+	if db.Error != nil {
+		return 0, postError(db.Error)
+	}
+	return db.RowsAffected, nil
+}
+
+/************************** Delete Posts by Author ****************************/
+func (p *Post) DeleteAuthorPosts(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Where("author_id=?", uid).Delete(&Post{})
 	if db.Error != nil {
-		if gorm.IsRecordNotFoundError(db.Error) {
-			return 0, errors.New("post not found")
-		}
-		return 0, db.Error
+		return 0, postError(db.Error)
 	}
 	return db.RowsAffected, nil
 }
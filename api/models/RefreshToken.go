@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RefreshToken is stored hashed. Every refresh rotates the token; all tokens
+// descending from the same login share a FamilyID so a replayed token can
+// revoke the whole chain.
+type RefreshToken struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	FamilyID  string     `gorm:"size:32;not null;index" json:"family_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"default:null" json:"created_at"`
+}
+
+/* ------------------- Save Refresh Token -----------------------*/
+func (t *RefreshToken) SaveRefreshToken(db *gorm.DB) (*RefreshToken, error) {
+	var err error
+	err = db.Create(&t).Error
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+	return t, nil
+}
+
+/* ------------------- Find Refresh Token by Hash -----------------------*/
+func (t *RefreshToken) FindRefreshTokenByHash(db *gorm.DB, hash string) (*RefreshToken, error) {
+	var err error
+	err = db.Model(RefreshToken{}).Where("token_hash=?", hash).Take(&t).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+	return t, nil
+}
+
+// Consume checks the token can be exchanged and revokes it. Presenting an
+// already revoked token is a reuse, the caller then revokes its whole family
+// once the transaction of the exchange is rolled back.
+func (t *RefreshToken) Consume(db *gorm.DB) error {
+	if t.RevokedAt != nil {
+		return ErrRefreshTokenReused
+	}
+	if t.ExpiresAt.Before(time.Now()) {
+		return ErrRefreshTokenInvalid
+	}
+
+	// Only one concurrent refresh may win the update
+	db = db.Model(&RefreshToken{}).Where("id=? AND revoked_at IS NULL", t.ID).UpdateColumn("revoked_at", time.Now())
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+/* ------------------- Revoke Refresh Tokens -----------------------*/
+func RevokeRefreshTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&RefreshToken{}).Where("family_id=? AND revoked_at IS NULL", familyID).UpdateColumn("revoked_at", time.Now()).Error
+}
+
+func RevokeUserRefreshTokens(db *gorm.DB, uid uint32) error {
+	return db.Model(&RefreshToken{}).Where("user_id=? AND revoked_at IS NULL", uid).UpdateColumn("revoked_at", time.Now()).Error
+}
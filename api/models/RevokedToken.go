@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RevokedToken is an access token id (jti) denied before its expiry.
+type RevokedToken struct {
+	JTI       string    `gorm:"primary_key;size:32" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// TokenDenyList is the database backed auth.DenyList, shared by every API instance.
+type TokenDenyList struct {
+	DB *gorm.DB
+}
+
+func NewTokenDenyList(db *gorm.DB) *TokenDenyList {
+	return &TokenDenyList{DB: db}
+}
+
+func (d *TokenDenyList) Deny(jti string, expiresAt time.Time) error {
+	// Expired tokens are rejected anyway, no need to keep them around
+	err := d.DB.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return err
+	}
+	return d.DB.Save(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (d *TokenDenyList) IsDenied(jti string) (bool, error) {
+	var count int
+	err := d.DB.Model(&RevokedToken{}).Where("jti=?", jti).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -1,22 +1,31 @@
 package models
 
 import (
-	"errors"
 	"html"
-	"log"
 	"strings"
 	"time"
 
-	"github.com/badoux/checkmail"
 	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// ValidRole tells whether role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
 type User struct {
 	ID        uint32    `gorm:"primary_key;auto_increment" json:"id"`
 	Nickname  string    `gorm:"size:255;not null;unique" json:"nickname"`
-	Email     string    `gorm:"size100;not null;unique" json:"email"`
-	Password  string    `gorm:"size:100;not null;" json:"password"`
+	Email     string    `gorm:"size:100;not null;unique" json:"email"`
+	Password  string    `gorm:"size:100;not null;" json:"password,omitempty"`
+	Role      string    `gorm:"size:20;not null;default:'user'" json:"role"`
 	CreatedAt time.Time `gorm:"default:null" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:null" json:"updated_at"`
 }
@@ -29,110 +38,110 @@ func VerifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func (u *User) BeforeSave() error {
-	hashedPassword, err := Hash(u.Password)
-	if err != nil {
-		return err
-	}
-
-	u.Password = string(hashedPassword)
-	return nil
-}
-
 func (u *User) Prepare() {
 	u.ID = 0
 	u.Nickname = html.EscapeString(strings.TrimSpace(u.Nickname))
 	u.Email = html.EscapeString(strings.TrimSpace(u.Email))
+	u.Role = RoleUser // Roles are only granted through UpdateUserRole
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 }
 
-func (u *User) Validate(action string) error {
-	switch strings.ToLower(action) {
-	case "update":
-		if u.Nickname == "" {
-			return errors.New("Required Nickname")
-		}
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
-	case "login":
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
-	default:
-		if u.Nickname == "" {
-			return errors.New("Required Nickname")
-		}
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
+// ValidateCreate checks a user signing up
+func (u *User) ValidateCreate() error {
+	v := Validator{}
+	u.validateProfile(&v)
+	if v.Required("password", u.Password) {
+		v.Password("password", u.Password)
+	}
+	return v.Err()
+}
+
+// ValidateUpdate checks a user replacing its profile and password
+func (u *User) ValidateUpdate() error {
+	return u.ValidateCreate()
+}
+
+// ValidateLogin only checks credentials are present, password rules may have changed since signing up
+func (u *User) ValidateLogin() error {
+	v := Validator{}
+	if v.Required("email", u.Email) {
+		v.Email("email", u.Email)
+	}
+	v.Required("password", u.Password)
+	return v.Err()
+}
+
+func (u *User) validateProfile(v *Validator) {
+	if v.Required("nickname", u.Nickname) {
+		v.Nickname("nickname", u.Nickname)
+		v.ColumnSize(u, "Nickname", "nickname", u.Nickname)
+	}
+	if v.Required("email", u.Email) {
+		v.Email("email", u.Email)
+		v.ColumnSize(u, "Email", "email", u.Email)
 	}
 }
 
 /* ------------------- Save User -----------------------*/
 func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 	var err error
-	err = db.Debug().Create(&u).Error
+	err = db.Create(&u).Error
 	if err != nil {
-		return &User{}, err
+		return &User{}, userError(err)
 	}
 	return u, nil
 }
 
 /* --------------------- Find All Users ------------------*/
-func (u *User) FindAllUsers(db *gorm.DB) (*[]User, error) {
+func (u *User) FindAllUsers(db *gorm.DB, page Pagination) (*[]User, *PageInfo, error) {
 	var err error
 	users := []User{}
-	err = db.Debug().Model(&User{}).Limit(100).Find(&users).Error
+	info := PageInfo{}
+	page.Normalize()
+
+	err = db.Model(&User{}).Count(&info.Total).Error
+	if err != nil {
+		return &[]User{}, &PageInfo{}, err
+	}
+	query, err := page.apply(db.Model(&User{}), DefaultSort)
 	if err != nil {
-		return &[]User{}, err
+		return &[]User{}, &PageInfo{}, err
+	}
+	err = query.Find(&users).Error
+	if err != nil {
+		return &[]User{}, &PageInfo{}, err
+	}
+	if len(users) > page.Limit {
+		users = users[:page.Limit]
+		last := users[len(users)-1]
+		info.NextCursor = NewCursor(DefaultSort, last.CreatedAt, uint64(last.ID)).Encode()
 	}
-	return &users, err
+	return &users, &info, err
 }
 
 /* ----------------------- Find User by ID --------------------*/
 func (u *User) FindUserById(db *gorm.DB, uid uint32) (*User, error) {
 	var err error
-	err = db.Debug().Model(User{}).Where("id=?", uid).Take(&u).Error
+	err = db.Model(User{}).Where("id=?", uid).Take(&u).Error
 	if err != nil {
-		return &User{}, err
+		return &User{}, userError(err)
 	}
-	if gorm.IsRecordNotFoundError(err) {
-		return &User{}, errors.New("User not found")
-	}
-	return u, err
+	return u, nil
 }
 
-/* ---------------------- Update User -------------------------*/
-func (u *User) UpdateUser(db *gorm.DB, uid uint32) (*User, error) {
-	err := u.BeforeSave()
+/* ----------------------- Find User by Email --------------------*/
+func (u *User) FindUserByEmail(db *gorm.DB, email string) (*User, error) {
+	err := db.Model(User{}).Where("email=?", email).Take(&u).Error
 	if err != nil {
-		log.Fatal(err)
+		return &User{}, userError(err)
 	}
+	return u, nil
+}
 
-	db = db.Debug().Model(&User{}).Where("id=?", uid).Take(&User{}).UpdateColumns(
+/* ---------------------- Update User -------------------------*/
+func (u *User) UpdateUser(db *gorm.DB, uid uint32) (*User, error) {
+	db = db.Model(&User{}).Where("id=?", uid).Take(&User{}).UpdateColumns(
 		map[string]interface{}{
 			"password":   u.Password,
 			"nickname":   u.Nickname,
@@ -142,22 +151,47 @@ func (u *User) UpdateUser(db *gorm.DB, uid uint32) (*User, error) {
 	)
 
 	if db.Error != nil {
-		return &User{}, db.Error
+		return &User{}, userError(db.Error)
 	}
 
-	err = db.Debug().Model(&User{}).Where("id=?", uid).Take(&u).Error
+	err := db.Model(&User{}).Where("id=?", uid).Take(&u).Error
 	if err != nil {
-		return &User{}, err
+		return &User{}, userError(err)
+	}
+	return u, nil
+}
+
+/* ---------------------- Update User Role -------------------------*/
+func (u *User) UpdateUserRole(db *gorm.DB, uid uint32, role string) (*User, error) {
+	db = db.Model(&User{}).Where("id=?", uid).Take(&User{}).UpdateColumns(
+		map[string]interface{}{
+			"role":       role,
+			"updated_at": time.Now(),
+		},
+	)
+	if db.Error != nil {
+		return &User{}, userError(db.Error)
+	}
+
+	err := db.Model(&User{}).Where("id=?", uid).Take(&u).Error
+	if err != nil {
+		return &User{}, userError(err)
 	}
 	return u, nil
 }
 
 /* ----------------------- Delete User -----------------------*/
+// DeleteUser removes the user with its refresh tokens, its posts are deleted before.
 func (u *User) DeleteUser(db *gorm.DB, uid uint32) (int64, error) {
-	db = db.Debug().Model(&User{}).Where("id=?", uid).Take(&User{}).Delete(&User{})
+	err := db.Where("user_id=?", uid).Delete(&RefreshToken{}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	db = db.Model(&User{}).Where("id=?", uid).Take(&User{}).Delete(&User{})
 
 	if db.Error != nil {
-		return 0, db.Error
+		return 0, userError(db.Error)
 	}
 
 	return db.RowsAffected, nil
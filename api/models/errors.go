@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/jinzhu/gorm"
+)
+
+// Errors returned by the models. Their codes are part of the API and must not change.
+var (
+	ErrUserNotFound       = exception.New(exception.NotFound, "user.not_found", "User not found")
+	ErrNicknameTaken      = exception.New(exception.Conflict, "user.nickname_taken", "Nickname already taken")
+	ErrEmailTaken         = exception.New(exception.Conflict, "user.email_taken", "Email already taken")
+	ErrInvalidCredentials = exception.New(exception.Unauthorized, "auth.invalid_credentials", "Incorrect email or password")
+
+	ErrPostNotFound  = exception.New(exception.NotFound, "post.not_found", "Post not found")
+	ErrTitleTaken    = exception.New(exception.Conflict, "post.title_taken", "Title already taken")
+	ErrUnknownAuthor = exception.New(exception.Invalid, "post.unknown_author", "Author does not exist").
+				WithFields(exception.FieldError{Field: "author_id", Rule: "exists", Message: "Author does not exist"})
+
+	ErrRefreshTokenInvalid = exception.New(exception.Unauthorized, "auth.refresh_token_invalid", "Invalid refresh token")
+	ErrRefreshTokenReused  = exception.New(exception.Unauthorized, "auth.refresh_token_reused", "Refresh token reused")
+
+	ErrInvalidCursor = exception.New(exception.Malformed, "query.invalid_cursor", "Invalid cursor")
+	ErrInvalidSort   = exception.New(exception.Malformed, "query.invalid_sort", "Invalid sort")
+)
+
+// userError turns database errors on users into typed errors
+func userError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrUserNotFound
+	}
+	if column, ok := exception.UniqueViolation(err); ok {
+		switch column {
+		case "nickname":
+			return ErrNicknameTaken.Wrap(err)
+		case "email":
+			return ErrEmailTaken.Wrap(err)
+		}
+	}
+	return err
+}
+
+// postError turns database errors on posts into typed errors
+func postError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrPostNotFound
+	}
+	if column, ok := exception.UniqueViolation(err); ok && column == "title" {
+		return ErrTitleTaken.Wrap(err)
+	}
+	// author_id is the only foreign key of posts
+	if _, ok := exception.ForeignKeyViolation(err); ok {
+		return ErrUnknownAuthor.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// timeSortFields are the sortable columns whose cursor value is a timestamp.
+var timeSortFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Sort is an ordering on a single column, with id as tie breaker.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort orders a collection by creation time, oldest first.
+var DefaultSort = Sort{Field: "created_at"}
+
+// ParseSort parses values like "title" or "-created_at" against a whitelist of columns.
+func ParseSort(value string, allowed []string) (Sort, error) {
+	if value == "" {
+		return DefaultSort, nil
+	}
+	sort := Sort{Field: strings.TrimPrefix(value, "-"), Desc: strings.HasPrefix(value, "-")}
+	for _, field := range allowed {
+		if field == sort.Field {
+			return sort, nil
+		}
+	}
+	return Sort{}, ErrInvalidSort
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Pagination selects a window of a collection.
+// When Cursor is set the page starts right after it and Offset is ignored.
+type Pagination struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// PageInfo is returned next to a page of results.
+type PageInfo struct {
+	Total      int
+	NextCursor string
+}
+
+// Cursor identifies the last row of a page for a given sort. It is handed to
+// clients as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint64 `json:"i"`
+}
+
+// NewCursor points right after the row with the given sort value and id.
+func NewCursor(sort Sort, value interface{}, id uint64) Cursor {
+	c := Cursor{Sort: sort.String(), ID: id}
+	if t, ok := value.(time.Time); ok {
+		c.Value = t.Format(time.RFC3339Nano)
+	} else {
+		c.Value = fmt.Sprint(value)
+	}
+	return c
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := Cursor{}
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ID == 0 || c.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// SortValue decodes the sort column value of the row the cursor points to.
+func (c Cursor) SortValue() (interface{}, error) {
+	if timeSortFields[strings.TrimPrefix(c.Sort, "-")] {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	}
+	return c.Value, nil
+}
+
+// Normalize applies the default limit and clamps out of range values.
+func (p *Pagination) Normalize() {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+}
+
+// apply adds ordering, the cursor/offset condition and the limit to db.
+// One extra row is requested so the caller can tell whether a next page exists.
+func (p Pagination) apply(db *gorm.DB, sort Sort) (*gorm.DB, error) {
+	dir, op := "asc", ">"
+	if sort.Desc {
+		dir, op = "desc", "<"
+	}
+
+	if sort.Field != "id" {
+		db = db.Order(sort.Field + " " + dir)
+	}
+	db = db.Order("id " + dir)
+
+	if p.Cursor != nil {
+		if p.Cursor.Sort != sort.String() {
+			return nil, ErrInvalidCursor
+		}
+		if sort.Field == "id" {
+			db = db.Where("id "+op+" ?", p.Cursor.ID)
+		} else {
+			value, err := p.Cursor.SortValue()
+			if err != nil {
+				return nil, err
+			}
+			db = db.Where(fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", sort.Field, op), value, value, p.Cursor.ID)
+		}
+	} else if p.Offset > 0 {
+		db = db.Offset(p.Offset)
+	}
+	return db.Limit(p.Limit + 1), nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// authorColumns are the users columns embedded in posts. The password hash is never selected.
+const authorColumns = "id, nickname, email, role, created_at, updated_at"
+
+// PostExpansion selects the relations embedded in post responses.
+type PostExpansion struct {
+	Author bool
+}
+
+// FindAuthors loads the users with the given ids, without their password hash.
+func FindAuthors(db *gorm.DB, ids []uint32) ([]User, error) {
+	authors := []User{}
+	if len(ids) == 0 {
+		return authors, nil
+	}
+	err := db.Model(&User{}).Select(authorColumns).Where("id IN (?)", ids).Find(&authors).Error
+	if err != nil {
+		return nil, err
+	}
+	return authors, nil
+}
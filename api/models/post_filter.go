@@ -0,0 +1,76 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PostSortFields are the columns GET /posts can be sorted by.
+var PostSortFields = []string{"created_at", "updated_at", "title", "id"}
+
+// PostFilter narrows down the posts returned by FindAllPosts.
+// Zero values are ignored.
+type PostFilter struct {
+	AuthorID      uint32
+	Query         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Sort          Sort
+}
+
+// likeEscaper escapes the LIKE wildcards using '!', which needs no quoting in any dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (f PostFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.AuthorID != 0 {
+		db = db.Where("author_id = ?", f.AuthorID)
+	}
+	if f.Query != "" {
+		// Title and content are stored html escaped by Prepare, so the search term is too
+		like := "%" + likeEscaper.Replace(strings.ToLower(html.EscapeString(f.Query))) + "%"
+		db = db.Where("LOWER(title) LIKE ? ESCAPE '!' OR LOWER(content) LIKE ? ESCAPE '!'", like, like)
+	}
+	if !f.CreatedAfter.IsZero() {
+		db = db.Where("created_at > ?", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", f.CreatedBefore)
+	}
+	return db
+}
+
+// Match is the in-memory counterpart of apply.
+func (f PostFilter) Match(p *Post) bool {
+	if f.AuthorID != 0 && p.AuthorID != f.AuthorID {
+		return false
+	}
+	if f.Query != "" {
+		query := strings.ToLower(html.EscapeString(f.Query))
+		if !strings.Contains(strings.ToLower(p.Title), query) && !strings.Contains(strings.ToLower(p.Content), query) {
+			return false
+		}
+	}
+	if !f.CreatedAfter.IsZero() && !p.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !p.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// SortValue is the value of the column field, as used in cursors.
+func (p *Post) SortValue(field string) interface{} {
+	switch field {
+	case "updated_at":
+		return p.UpdatedAt
+	case "title":
+		return p.Title
+	case "id":
+		return p.ID
+	}
+	return p.CreatedAt
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/badoux/checkmail"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	PasswordMinLength = 8
+	// bcrypt ignores everything after 72 bytes
+	PasswordMaxBytes  = 72
+	NicknameMinLength = 3
+)
+
+// Validator collects every failed rule instead of stopping at the first one.
+type Validator struct {
+	fields []exception.FieldError
+}
+
+func (v *Validator) Add(field, rule, message string) {
+	v.fields = append(v.fields, exception.FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// Check records a failure of rule on field unless ok.
+func (v *Validator) Check(ok bool, field, rule, message string) bool {
+	if !ok {
+		v.Add(field, rule, message)
+	}
+	return ok
+}
+
+// Required fails on empty values. The other rules skip empty values so a
+// missing field is only reported once.
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(value != "", field, "required", fmt.Sprintf("Required %s", field))
+}
+
+func (v *Validator) MinLength(field, value string, min int) bool {
+	return v.Check(value == "" || utf8.RuneCountInString(value) >= min, field, "min_length",
+		fmt.Sprintf("%s must be at least %d characters long", field, min))
+}
+
+func (v *Validator) MaxLength(field, value string, max int) bool {
+	return v.Check(utf8.RuneCountInString(value) <= max, field, "max_length",
+		fmt.Sprintf("%s must be at most %d characters long", field, max))
+}
+
+// ColumnSize checks value fits the gorm size tag of the model field.
+func (v *Validator) ColumnSize(model interface{}, fieldName, field, value string) bool {
+	size := columnSize(model, fieldName)
+	if size == 0 {
+		return true
+	}
+	return v.MaxLength(field, value, size)
+}
+
+func (v *Validator) Email(field, value string) bool {
+	return v.Check(value == "" || checkmail.ValidateFormat(value) == nil, field, "email", "Invalid Email")
+}
+
+// Password requires PasswordMinLength characters mixing letters and digits.
+func (v *Validator) Password(field, value string) bool {
+	if value == "" {
+		return true
+	}
+	var letter, digit bool
+	for _, r := range value {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	ok := v.MinLength(field, value, PasswordMinLength)
+	ok = v.Check(len(value) <= PasswordMaxBytes, field, "max_length",
+		fmt.Sprintf("%s must be at most %d bytes long", field, PasswordMaxBytes)) && ok
+	return v.Check(letter && digit, field, "password_strength",
+		fmt.Sprintf("%s must contain letters and digits", field)) && ok
+}
+
+// Nickname allows letters, digits, spaces and . _ -
+func (v *Validator) Nickname(field, value string) bool {
+	valid := true
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '.' && r != '_' && r != '-' {
+			valid = false
+			break
+		}
+	}
+	ok := v.MinLength(field, value, NicknameMinLength)
+	return v.Check(valid, field, "nickname_charset",
+		fmt.Sprintf("%s may only contain letters, digits, spaces, dots, dashes and underscores", field)) && ok
+}
+
+// Err returns nil when every rule passed, or a validation error listing all failures.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return exception.ErrValidation.WithFields(v.fields...)
+}
+
+// columnSize reads the size gorm tag of the model field, 0 when there is none
+func columnSize(model interface{}, fieldName string) int {
+	for _, field := range (&gorm.Scope{Value: model}).GetModelStruct().StructFields {
+		if field.Name != fieldName {
+			continue
+		}
+		if size, ok := field.TagSettingsGet("SIZE"); ok {
+			n, _ := strconv.Atoi(size)
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/antonio91capa/go-apirest/api/database"
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+type txKey struct{}
+
+// GormTransactor runs transactions on db, seen by the gorm repositories of db.
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{db: db}
+}
+
+// InTransaction joins the transaction already in ctx, if any.
+func (t *GormTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	tx := conn(t.db, ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// conn is the transaction of ctx or db, tracing the queries under the span of ctx.
+func conn(db *gorm.DB, ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+	return database.WithContext(db, ctx)
+}
+
+// GormUserRepository stores the users through the models, tracing every
+// query under the span of ctx.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	return user.SaveUser(conn(r.db, ctx))
+}
+
+func (r *GormUserRepository) FindAll(ctx context.Context, page models.Pagination) ([]models.User, *models.PageInfo, error) {
+	users, info, err := (&models.User{}).FindAllUsers(conn(r.db, ctx), page)
+	return *users, info, err
+}
+
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint32) (*models.User, error) {
+	return (&models.User{}).FindUserById(conn(r.db, ctx), id)
+}
+
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return (&models.User{}).FindUserByEmail(conn(r.db, ctx), email)
+}
+
+func (r *GormUserRepository) FindAuthors(ctx context.Context, ids []uint32) ([]models.User, error) {
+	return models.FindAuthors(conn(r.db, ctx), ids)
+}
+
+func (r *GormUserRepository) Update(ctx context.Context, id uint32, user *models.User) (*models.User, error) {
+	return user.UpdateUser(conn(r.db, ctx), id)
+}
+
+func (r *GormUserRepository) UpdateRole(ctx context.Context, id uint32, role string) (*models.User, error) {
+	return (&models.User{}).UpdateUserRole(conn(r.db, ctx), id, role)
+}
+
+func (r *GormUserRepository) Delete(ctx context.Context, id uint32) error {
+	_, err := (&models.User{}).DeleteUser(conn(r.db, ctx), id)
+	return err
+}
+
+// GormPostRepository stores the posts through the models.
+type GormPostRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{db: db}
+}
+
+func (r *GormPostRepository) Create(ctx context.Context, post *models.Post) (*models.Post, error) {
+	return post.SavePost(conn(r.db, ctx))
+}
+
+func (r *GormPostRepository) FindAll(ctx context.Context, filter models.PostFilter, page models.Pagination) ([]models.Post, *models.PageInfo, error) {
+	posts, info, err := (&models.Post{}).FindAllPosts(conn(r.db, ctx), filter, page)
+	return *posts, info, err
+}
+
+func (r *GormPostRepository) FindByID(ctx context.Context, id uint64) (*models.Post, error) {
+	return (&models.Post{}).FindPostByID(conn(r.db, ctx), id)
+}
+
+// Update reloads the post so the stored creation time is returned.
+func (r *GormPostRepository) Update(ctx context.Context, post *models.Post) (*models.Post, error) {
+	db := conn(r.db, ctx)
+	if _, err := (&models.Post{}).FindPostByID(db, post.ID); err != nil {
+		return &models.Post{}, err
+	}
+	if _, err := post.UpdatePost(db); err != nil {
+		return &models.Post{}, err
+	}
+	return (&models.Post{}).FindPostByID(db, post.ID)
+}
+
+func (r *GormPostRepository) Delete(ctx context.Context, id uint64) error {
+	db := conn(r.db, ctx)
+	post := models.Post{}
+	if _, err := post.FindPostByID(db, id); err != nil {
+		return err
+	}
+	_, err := post.DeletePost(db, id, post.AuthorID)
+	return err
+}
+
+func (r *GormPostRepository) DeleteByAuthor(ctx context.Context, authorID uint32) error {
+	_, err := (&models.Post{}).DeleteAuthorPosts(conn(r.db, ctx), authorID)
+	return err
+}
+
+// GormRefreshTokenRepository stores the refresh tokens through the models.
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRefreshTokenRepository(db *gorm.DB) *GormRefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+func (r *GormRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	return token.SaveRefreshToken(conn(r.db, ctx))
+}
+
+func (r *GormRefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	return (&models.RefreshToken{}).FindRefreshTokenByHash(conn(r.db, ctx), hash)
+}
+
+func (r *GormRefreshTokenRepository) Consume(ctx context.Context, token *models.RefreshToken) error {
+	return token.Consume(conn(r.db, ctx))
+}
+
+func (r *GormRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return models.RevokeRefreshTokenFamily(conn(r.db, ctx), familyID)
+}
+
+func (r *GormRefreshTokenRepository) RevokeUser(ctx context.Context, userID uint32) error {
+	return models.RevokeUserRefreshTokens(conn(r.db, ctx), userID)
+}
@@ -0,0 +1,593 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// Memory keeps users, posts and refresh tokens in maps, for tests and
+// development without a database. It enforces the same unique columns as the schema.
+type Memory struct {
+	// tx serializes transactions, mu guards every access
+	tx          sync.Mutex
+	mu          sync.RWMutex
+	users       map[uint32]models.User
+	posts       map[uint64]models.Post
+	tokens      map[uint64]models.RefreshToken
+	nextUserID  uint32
+	nextPostID  uint64
+	nextTokenID uint64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:  map[uint32]models.User{},
+		posts:  map[uint64]models.Post{},
+		tokens: map[uint64]models.RefreshToken{},
+	}
+}
+
+// InTransaction undoes the changes of fn when it fails. Only the rows fn wrote
+// are restored, the writes of other goroutines are kept, and like database
+// sequences the ids taken by fn are not reused.
+func (m *Memory) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.transaction(ctx) != nil {
+		return fn(ctx)
+	}
+	m.tx.Lock()
+	defer m.tx.Unlock()
+
+	tx := &memoryTx{
+		m:      m,
+		users:  map[uint32]*models.User{},
+		posts:  map[uint64]*models.Post{},
+		tokens: map[uint64]*models.RefreshToken{},
+	}
+	err := fn(context.WithValue(ctx, memoryTxKey{}, tx))
+	if err != nil {
+		m.mu.Lock()
+		tx.rollback()
+		m.mu.Unlock()
+	}
+	return err
+}
+
+type memoryTxKey struct{}
+
+// memoryTx is the undo log of a transaction: the rows it changed as they were
+// before, nil when they did not exist.
+type memoryTx struct {
+	m      *Memory
+	users  map[uint32]*models.User
+	posts  map[uint64]*models.Post
+	tokens map[uint64]*models.RefreshToken
+}
+
+// transaction returns the transaction of m in ctx, or nil.
+func (m *Memory) transaction(ctx context.Context) *memoryTx {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.m == m {
+		return tx
+	}
+	return nil
+}
+
+func (tx *memoryTx) rollback() {
+	m := tx.m
+	for id, user := range tx.users {
+		if user == nil {
+			delete(m.users, id)
+		} else {
+			m.users[id] = *user
+		}
+	}
+	for id, post := range tx.posts {
+		if post == nil {
+			delete(m.posts, id)
+		} else {
+			m.posts[id] = *post
+		}
+	}
+	for id, token := range tx.tokens {
+		if token == nil {
+			delete(m.tokens, id)
+		} else {
+			m.tokens[id] = *token
+		}
+	}
+}
+
+// putUser stores user, or deletes the row id when nil, recording the previous
+// row in the transaction of ctx. mu must be held.
+func (m *Memory) putUser(ctx context.Context, id uint32, user *models.User) {
+	if tx := m.transaction(ctx); tx != nil {
+		if _, logged := tx.users[id]; !logged {
+			if previous, exists := m.users[id]; exists {
+				tx.users[id] = &previous
+			} else {
+				tx.users[id] = nil
+			}
+		}
+	}
+	if user == nil {
+		delete(m.users, id)
+	} else {
+		m.users[id] = *user
+	}
+}
+
+// putPost is putUser for the posts.
+func (m *Memory) putPost(ctx context.Context, id uint64, post *models.Post) {
+	if tx := m.transaction(ctx); tx != nil {
+		if _, logged := tx.posts[id]; !logged {
+			if previous, exists := m.posts[id]; exists {
+				tx.posts[id] = &previous
+			} else {
+				tx.posts[id] = nil
+			}
+		}
+	}
+	if post == nil {
+		delete(m.posts, id)
+	} else {
+		m.posts[id] = *post
+	}
+}
+
+// putToken is putUser for the refresh tokens.
+func (m *Memory) putToken(ctx context.Context, id uint64, token *models.RefreshToken) {
+	if tx := m.transaction(ctx); tx != nil {
+		if _, logged := tx.tokens[id]; !logged {
+			if previous, exists := m.tokens[id]; exists {
+				tx.tokens[id] = &previous
+			} else {
+				tx.tokens[id] = nil
+			}
+		}
+	}
+	if token == nil {
+		delete(m.tokens, id)
+	} else {
+		m.tokens[id] = *token
+	}
+}
+
+// Users returns the repository of the users of m.
+func (m *Memory) Users() UserRepository {
+	return memoryUsers{m}
+}
+
+// Posts returns the repository of the posts of m.
+func (m *Memory) Posts() PostRepository {
+	return memoryPosts{m}
+}
+
+// RefreshTokens returns the repository of the refresh tokens of m.
+func (m *Memory) RefreshTokens() RefreshTokenRepository {
+	return memoryRefreshTokens{m}
+}
+
+// ****** Users
+type memoryUsers struct {
+	m *Memory
+}
+
+// uniqueUser fails like the unique indexes on nickname and email, ignoring the row id
+func (m *Memory) uniqueUser(user *models.User, id uint32) error {
+	for _, other := range m.users {
+		if other.ID == id {
+			continue
+		}
+		if other.Nickname == user.Nickname {
+			return models.ErrNicknameTaken
+		}
+		if other.Email == user.Email {
+			return models.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+func (r memoryUsers) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.m.uniqueUser(user, 0); err != nil {
+		return &models.User{}, err
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	r.m.nextUserID++
+	user.ID = r.m.nextUserID
+	r.m.putUser(ctx, user.ID, user)
+	return user, nil
+}
+
+func (r memoryUsers) FindAll(ctx context.Context, page models.Pagination) ([]models.User, *models.PageInfo, error) {
+	r.m.mu.RLock()
+	users := make([]models.User, 0, len(r.m.users))
+	for _, user := range r.m.users {
+		users = append(users, user)
+	}
+	r.m.mu.RUnlock()
+
+	return paginate(users, models.DefaultSort, page, func(u models.User) (interface{}, uint64) {
+		return u.CreatedAt, uint64(u.ID)
+	})
+}
+
+func (r memoryUsers) FindByID(ctx context.Context, id uint32) (*models.User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	user, ok := r.m.users[id]
+	if !ok {
+		return &models.User{}, models.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	for _, user := range r.m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return &models.User{}, models.ErrUserNotFound
+}
+
+func (r memoryUsers) FindAuthors(ctx context.Context, ids []uint32) ([]models.User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	authors := []models.User{}
+	for _, id := range ids {
+		if user, ok := r.m.users[id]; ok {
+			user.Password = ""
+			authors = append(authors, user)
+		}
+	}
+	return authors, nil
+}
+
+func (r memoryUsers) Update(ctx context.Context, id uint32, user *models.User) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.users[id]
+	if !ok {
+		return &models.User{}, models.ErrUserNotFound
+	}
+	if err := r.m.uniqueUser(user, id); err != nil {
+		return &models.User{}, err
+	}
+	stored.Nickname = user.Nickname
+	stored.Email = user.Email
+	stored.Password = user.Password
+	stored.UpdatedAt = time.Now()
+	r.m.putUser(ctx, id, &stored)
+	return &stored, nil
+}
+
+func (r memoryUsers) UpdateRole(ctx context.Context, id uint32, role string) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	stored, ok := r.m.users[id]
+	if !ok {
+		return &models.User{}, models.ErrUserNotFound
+	}
+	stored.Role = role
+	stored.UpdatedAt = time.Now()
+	r.m.putUser(ctx, id, &stored)
+	return &stored, nil
+}
+
+func (r memoryUsers) Delete(ctx context.Context, id uint32) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.users[id]; !ok {
+		return models.ErrUserNotFound
+	}
+	r.m.putUser(ctx, id, nil)
+	// Like the foreign key of refresh_tokens, deleted on cascade
+	for tokenID, token := range r.m.tokens {
+		if token.UserID == id {
+			r.m.putToken(ctx, tokenID, nil)
+		}
+	}
+	return nil
+}
+
+// ****** Posts
+type memoryPosts struct {
+	m *Memory
+}
+
+func (m *Memory) uniquePost(post *models.Post, id uint64) error {
+	for _, other := range m.posts {
+		if other.ID != id && other.Title == post.Title {
+			return models.ErrTitleTaken
+		}
+	}
+	return nil
+}
+
+func (r memoryPosts) Create(ctx context.Context, post *models.Post) (*models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if _, ok := r.m.users[post.AuthorID]; !ok {
+		return &models.Post{}, models.ErrUnknownAuthor
+	}
+	if err := r.m.uniquePost(post, 0); err != nil {
+		return &models.Post{}, err
+	}
+	now := time.Now()
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = now
+	}
+	if post.UpdatedAt.IsZero() {
+		post.UpdatedAt = now
+	}
+	r.m.nextPostID++
+	post.ID = r.m.nextPostID
+	stored := *post
+	stored.Author = nil
+	r.m.putPost(ctx, post.ID, &stored)
+	return post, nil
+}
+
+func (r memoryPosts) FindAll(ctx context.Context, filter models.PostFilter, page models.Pagination) ([]models.Post, *models.PageInfo, error) {
+	if filter.Sort.Field == "" {
+		filter.Sort = models.DefaultSort
+	}
+
+	r.m.mu.RLock()
+	posts := []models.Post{}
+	for _, post := range r.m.posts {
+		if filter.Match(&post) {
+			posts = append(posts, post)
+		}
+	}
+	r.m.mu.RUnlock()
+
+	return paginate(posts, filter.Sort, page, func(p models.Post) (interface{}, uint64) {
+		return p.SortValue(filter.Sort.Field), p.ID
+	})
+}
+
+func (r memoryPosts) FindByID(ctx context.Context, id uint64) (*models.Post, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	post, ok := r.m.posts[id]
+	if !ok {
+		return &models.Post{}, models.ErrPostNotFound
+	}
+	return &post, nil
+}
+
+func (r memoryPosts) Update(ctx context.Context, post *models.Post) (*models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.posts[post.ID]
+	if !ok {
+		return &models.Post{}, models.ErrPostNotFound
+	}
+	if err := r.m.uniquePost(post, post.ID); err != nil {
+		return &models.Post{}, err
+	}
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.UpdatedAt = time.Now()
+	r.m.putPost(ctx, post.ID, &stored)
+	return &stored, nil
+}
+
+func (r memoryPosts) Delete(ctx context.Context, id uint64) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.posts[id]; !ok {
+		return models.ErrPostNotFound
+	}
+	r.m.putPost(ctx, id, nil)
+	return nil
+}
+
+func (r memoryPosts) DeleteByAuthor(ctx context.Context, authorID uint32) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for id, post := range r.m.posts {
+		if post.AuthorID == authorID {
+			r.m.putPost(ctx, id, nil)
+		}
+	}
+	return nil
+}
+
+// ****** Refresh tokens
+type memoryRefreshTokens struct {
+	m *Memory
+}
+
+func (r memoryRefreshTokens) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if _, ok := r.m.users[token.UserID]; !ok {
+		return &models.RefreshToken{}, models.ErrUserNotFound
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.m.nextTokenID++
+	token.ID = r.m.nextTokenID
+	r.m.putToken(ctx, token.ID, token)
+	return token, nil
+}
+
+func (r memoryRefreshTokens) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	for _, token := range r.m.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return &models.RefreshToken{}, models.ErrRefreshTokenInvalid
+}
+
+func (r memoryRefreshTokens) Consume(ctx context.Context, token *models.RefreshToken) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.tokens[token.ID]
+	if !ok {
+		return models.ErrRefreshTokenInvalid
+	}
+	if stored.RevokedAt != nil {
+		return models.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	if stored.ExpiresAt.Before(now) {
+		return models.ErrRefreshTokenInvalid
+	}
+	stored.RevokedAt = &now
+	r.m.putToken(ctx, token.ID, &stored)
+	return nil
+}
+
+func (r memoryRefreshTokens) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revoke(ctx, func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (r memoryRefreshTokens) RevokeUser(ctx context.Context, userID uint32) error {
+	return r.revoke(ctx, func(token models.RefreshToken) bool { return token.UserID == userID })
+}
+
+// revoke revokes the tokens selected by match which are not revoked yet
+func (r memoryRefreshTokens) revoke(ctx context.Context, match func(models.RefreshToken) bool) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	now := time.Now()
+	for id, token := range r.m.tokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			r.m.putToken(ctx, id, &token)
+		}
+	}
+	return nil
+}
+
+// paginate sorts rows and cuts the page selected by page, the in-memory
+// counterpart of the ORDER BY, cursor condition and LIMIT of the models.
+func paginate[T any](rows []T, by models.Sort, page models.Pagination, key func(T) (interface{}, uint64)) ([]T, *models.PageInfo, error) {
+	page.Normalize()
+	info := &models.PageInfo{Total: len(rows)}
+
+	// before tells whether the row (value, id) comes first in the sort order
+	before := func(a interface{}, aID uint64, b interface{}, bID uint64) bool {
+		c := 0
+		if by.Field != "id" {
+			c = compare(a, b)
+		}
+		if c == 0 {
+			c = compare(aID, bID)
+		}
+		if by.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, aID := key(rows[i])
+		b, bID := key(rows[j])
+		return before(a, aID, b, bID)
+	})
+
+	if page.Cursor != nil {
+		if page.Cursor.Sort != by.String() {
+			return nil, nil, models.ErrInvalidCursor
+		}
+		value, err := page.Cursor.SortValue()
+		if err != nil {
+			return nil, nil, err
+		}
+		start := len(rows)
+		for i, row := range rows {
+			v, id := key(row)
+			if by.Field != "id" {
+				v = normalize(v, value)
+			}
+			if before(value, page.Cursor.ID, v, id) {
+				start = i
+				break
+			}
+		}
+		rows = rows[start:]
+	} else if page.Offset > 0 {
+		if page.Offset > len(rows) {
+			page.Offset = len(rows)
+		}
+		rows = rows[page.Offset:]
+	}
+
+	if len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+		value, id := key(rows[len(rows)-1])
+		info.NextCursor = models.NewCursor(by, value, id).Encode()
+	}
+	return rows, info, nil
+}
+
+// normalize turns v into the type of the decoded cursor value, which is a
+// string for every column but the timestamps.
+func normalize(v, cursor interface{}) interface{} {
+	if _, ok := cursor.(string); ok {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		b := b.(time.Time)
+		switch {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+		return 0
+	case uint64:
+		b := b.(uint64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	x, y := fmt.Sprint(a), fmt.Sprint(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+func TestMemoryRollbackKeepsOtherWrites(t *testing.T) {
+	ctx := context.Background()
+	memory := repository.NewMemory()
+	users, posts := memory.Users(), memory.Posts()
+	alice, err := users.Create(ctx, &models.User{Nickname: "alice", Email: "alice@mail.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := posts.Create(ctx, &models.Post{Title: "Alice post", AuthorID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	var bob *models.User
+	err = memory.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := users.UpdateRole(ctx, alice.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+		if err := posts.DeleteByAuthor(ctx, alice.ID); err != nil {
+			return err
+		}
+		if _, err := posts.Create(ctx, &models.Post{Title: "Rolled back", AuthorID: alice.ID}); err != nil {
+			return err
+		}
+
+		// Another request writes outside of the transaction meanwhile
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			bob, err = users.Create(context.Background(), &models.User{Nickname: "bob", Email: "bob@mail.com"})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		wg.Wait()
+		return failed
+	})
+	assert.Equal(t, err, failed)
+
+	restored, err := users.FindByID(ctx, alice.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored.Role, models.RoleUser)
+	_, err = posts.FindByID(ctx, post.ID)
+	assert.Equal(t, err, nil)
+	all, info, err := posts.FindAll(ctx, models.PostFilter{}, models.Pagination{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, info.Total, 1)
+	assert.Equal(t, all[0].Title, "Alice post")
+
+	_, err = users.FindByID(ctx, bob.ID)
+	assert.Equal(t, err, nil)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// UserRepository stores the users, whose passwords are already hashed. Its
+// errors are the ones of the models package, such as models.ErrUserNotFound
+// or models.ErrEmailTaken.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) (*models.User, error)
+	FindAll(ctx context.Context, page models.Pagination) ([]models.User, *models.PageInfo, error)
+	FindByID(ctx context.Context, id uint32) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// FindAuthors returns the users with the given ids, without their password hash.
+	FindAuthors(ctx context.Context, ids []uint32) ([]models.User, error)
+	// Update replaces the nickname, email and password of the user id.
+	Update(ctx context.Context, id uint32, user *models.User) (*models.User, error)
+	UpdateRole(ctx context.Context, id uint32, role string) (*models.User, error)
+	// Delete removes the user id, whose posts are deleted before.
+	Delete(ctx context.Context, id uint32) error
+}
+
+// PostRepository stores the posts, failing with models.ErrPostNotFound or
+// models.ErrTitleTaken.
+type PostRepository interface {
+	Create(ctx context.Context, post *models.Post) (*models.Post, error)
+	FindAll(ctx context.Context, filter models.PostFilter, page models.Pagination) ([]models.Post, *models.PageInfo, error)
+	FindByID(ctx context.Context, id uint64) (*models.Post, error)
+	// Update replaces the title and content of the post post.ID.
+	Update(ctx context.Context, post *models.Post) (*models.Post, error)
+	Delete(ctx context.Context, id uint64) error
+	// DeleteByAuthor removes every post of the user authorID.
+	DeleteByAuthor(ctx context.Context, authorID uint32) error
+}
+
+// RefreshTokenRepository stores the refresh tokens by hash, failing with
+// models.ErrRefreshTokenInvalid for an unknown token.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error)
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// Consume revokes token, failing with models.ErrRefreshTokenReused when it
+	// was already revoked, by an earlier or a concurrent call.
+	Consume(ctx context.Context, token *models.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint32) error
+}
+
+// Transactor runs fn in a transaction, committed when fn returns nil and
+// rolled back otherwise. The repository calls made with the ctx given to fn
+// take part in the transaction.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ExpandPosts loads the relations selected by e for all posts with one lookup per relation.
+func ExpandPosts(ctx context.Context, users UserRepository, posts []models.Post, e models.PostExpansion) error {
+	if !e.Author || len(posts) == 0 {
+		return nil
+	}
+
+	ids := []uint32{}
+	seen := map[uint32]bool{}
+	for _, post := range posts {
+		if !seen[post.AuthorID] {
+			seen[post.AuthorID] = true
+			ids = append(ids, post.AuthorID)
+		}
+	}
+
+	authors, err := users.FindAuthors(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := map[uint32]*models.User{}
+	for i := range authors {
+		byID[authors[i].ID] = &authors[i]
+	}
+	for i := range posts {
+		posts[i].Author = byID[posts[i].AuthorID]
+	}
+	return nil
+}
+
+// ExpandPost loads the relations selected by e into post.
+func ExpandPost(ctx context.Context, users UserRepository, post *models.Post, e models.PostExpansion) error {
+	posts := []models.Post{*post}
+	err := ExpandPosts(ctx, users, posts, e)
+	if err != nil {
+		return err
+	}
+	post.Author = posts[0].Author
+	return nil
+}
@@ -0,0 +1,19 @@
+package responses
+
+// Health is the body of the liveness and readiness probes.
+type Health struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Check is the result of one readiness dependency.
+type Check struct {
+	Status  string      `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
@@ -0,0 +1,10 @@
+package responses
+
+// Page is the envelope used for every paginated collection.
+type Page struct {
+	Data       interface{} `json:"data"`
+	Total      int         `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
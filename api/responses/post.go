@@ -0,0 +1,43 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// Post is the representation of a models.Post. The author is only present
+// when it was expanded.
+type Post struct {
+	ID        uint64      `json:"id"`
+	Title     string      `json:"title"`
+	Content   string      `json:"content"`
+	AuthorID  uint32      `json:"author_id"`
+	Author    *PublicUser `json:"author,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func NewPost(p *models.Post) Post {
+	post := Post{
+		ID:        p.ID,
+		Title:     p.Title,
+		Content:   p.Content,
+		AuthorID:  p.AuthorID,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+	if p.Author != nil {
+		author := NewPublicUser(p.Author)
+		post.Author = &author
+	}
+	return post
+}
+
+func NewPosts(posts []models.Post) []Post {
+	views := make([]Post, len(posts))
+	for i := range posts {
+		views[i] = NewPost(&posts[i])
+	}
+	return views
+}
@@ -2,8 +2,12 @@ package responses
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
 )
 
 func ResponseJSON(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -14,14 +18,74 @@ func ResponseJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
-func Error(w http.ResponseWriter, statusCode int, err error) {
-	if err != nil {
-		ResponseJSON(w, statusCode, struct {
-			Error string `json:"error"`
-		}{
-			Error: err.Error(),
-		})
-		return
+// Problem is an RFC 7807 problem details document. Code is stable and meant
+// for clients to branch on; Title and Detail are human readable.
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	Code     string                 `json:"code"`
+	Errors   []exception.FieldError `json:"errors,omitempty"`
+}
+
+var kindStatus = map[exception.Kind]int{
+	exception.Internal:     http.StatusInternalServerError,
+	exception.Malformed:    http.StatusBadRequest,
+	exception.Invalid:      http.StatusUnprocessableEntity,
+	exception.Unauthorized: http.StatusUnauthorized,
+	exception.Forbidden:    http.StatusForbidden,
+	exception.NotFound:     http.StatusNotFound,
+	exception.Conflict:     http.StatusConflict,
+}
+
+// statusCode is the generic code of errors without one
+var statusCode = map[int]string{
+	http.StatusBadRequest:          "request.bad_request",
+	http.StatusUnauthorized:        exception.ErrUnauthorized.Code,
+	http.StatusForbidden:           exception.ErrForbidden.Code,
+	http.StatusNotFound:            exception.ErrNotFound.Code,
+	http.StatusConflict:            "request.conflict",
+	http.StatusUnprocessableEntity: "request.unprocessable",
+}
+
+// NewProblem describes err. Errors from the exception package set the status
+// from their kind; any other error is internal and answered with a 500,
+// whatever status is given, since it may carry SQL or driver messages.
+// Internal details are never exposed.
+func NewProblem(status int, err error) Problem {
+	problem := Problem{Status: status}
+
+	coded := &exception.Error{}
+	if errors.As(err, &coded) {
+		problem.Status = kindStatus[coded.Kind]
+		problem.Code = coded.Code
+		problem.Detail = coded.Message
+		problem.Errors = coded.Fields
+	} else if err != nil {
+		problem.Status = http.StatusInternalServerError
+	} else {
+		problem.Code = statusCode[status]
+	}
+
+	if problem.Status >= http.StatusInternalServerError || problem.Code == "" {
+		problem.Code = exception.ErrInternal.Code
+		problem.Detail = exception.ErrInternal.Message
+		problem.Errors = nil
+	}
+	problem.Title = http.StatusText(problem.Status)
+	problem.Type = "urn:problem-type:" + problem.Code
+	return problem
+}
+
+// Error writes err as an application/problem+json response. Internal errors
+// are logged with the context of r.
+func Error(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	problem := NewProblem(statusCode, err)
+	if problem.Code == exception.ErrInternal.Code && err != nil {
+		slog.ErrorContext(r.Context(), "internal error", "error", err)
 	}
-	ResponseJSON(w, http.StatusBadRequest, nil)
+	w.Header().Set("Content-Type", "application/problem+json")
+	ResponseJSON(w, problem.Status, problem)
 }
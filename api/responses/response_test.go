@@ -0,0 +1,77 @@
+package responses
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/exception"
+)
+
+func TestNewProblem(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		err    error
+		want   int
+		code   string
+		detail string
+	}{
+		{"coded error", http.StatusInternalServerError, exception.ErrForbidden, http.StatusForbidden, "auth.forbidden", "Forbidden"},
+		{"wrapped coded error", http.StatusBadRequest, exception.ErrInvalidBody.Wrap(errors.New("unexpected EOF")), http.StatusUnprocessableEntity, "request.invalid_body", "Invalid request body"},
+		{"plain error with a 4xx", http.StatusBadRequest, errors.New("dial tcp 10.0.0.1:5432: connection refused"), http.StatusInternalServerError, "internal", exception.ErrInternal.Message},
+		{"plain error with a 5xx", http.StatusInternalServerError, errors.New("sql: database is closed"), http.StatusInternalServerError, "internal", exception.ErrInternal.Message},
+		{"no error", http.StatusNotFound, nil, http.StatusNotFound, "resource.not_found", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			problem := NewProblem(c.status, c.err)
+			assert.Equal(t, problem.Status, c.want)
+			assert.Equal(t, problem.Code, c.code)
+			assert.Equal(t, problem.Detail, c.detail)
+		})
+	}
+}
+
+func TestErrorWritesProblemJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := exception.ErrValidation.WithFields(exception.FieldError{Field: "title", Rule: "required", Message: "Required title"})
+	Error(rec, httptest.NewRequest("POST", "/v1/posts", nil), http.StatusBadRequest, err)
+
+	assert.Equal(t, rec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(t, rec.Header().Get("Content-Type"), "application/problem+json")
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, body["type"], "urn:problem-type:validation.failed")
+	assert.Equal(t, body["title"], "Unprocessable Entity")
+	assert.Equal(t, body["status"], float64(http.StatusUnprocessableEntity))
+	assert.Equal(t, body["code"], "validation.failed")
+	assert.Equal(t, body["detail"], "Validation failed")
+	assert.Equal(t, body["errors"], []interface{}{
+		map[string]interface{}{"field": "title", "rule": "required", "message": "Required title"},
+	})
+}
+
+func TestErrorLogsInternalErrors(t *testing.T) {
+	logs := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	rec := httptest.NewRecorder()
+	Error(rec, httptest.NewRequest("GET", "/v1/users", nil), http.StatusInternalServerError, errors.New("sql: database is closed"))
+
+	assert.Equal(t, rec.Code, http.StatusInternalServerError)
+	assert.Equal(t, rec.Header().Get("Content-Type"), "application/problem+json")
+	assert.Equal(t, strings.Contains(rec.Body.String(), "database is closed"), false)
+	assert.Equal(t, strings.Contains(logs.String(), "database is closed"), true)
+}
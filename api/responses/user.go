@@ -0,0 +1,56 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/models"
+)
+
+// PublicUser is what anybody can see about a user.
+type PublicUser struct {
+	ID        uint32    `json:"id"`
+	Nickname  string    `json:"nickname"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PrivateUser is what users see about themselves.
+type PrivateUser struct {
+	PublicUser
+	Email string `json:"email"`
+}
+
+func NewPublicUser(u *models.User) PublicUser {
+	return PublicUser{
+		ID:        u.ID,
+		Nickname:  u.Nickname,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+func NewPrivateUser(u *models.User) PrivateUser {
+	return PrivateUser{
+		PublicUser: NewPublicUser(u),
+		Email:      u.Email,
+	}
+}
+
+// NewUserView returns the private representation when the viewer is the user
+// and the public one otherwise. A viewerID of 0 means an anonymous viewer.
+func NewUserView(u *models.User, viewerID uint32) interface{} {
+	if viewerID != 0 && viewerID == u.ID {
+		return NewPrivateUser(u)
+	}
+	return NewPublicUser(u)
+}
+
+func NewUserViews(users []models.User, viewerID uint32) []interface{} {
+	views := make([]interface{}, len(users))
+	for i := range users {
+		views[i] = NewUserView(&users[i], viewerID)
+	}
+	return views
+}
@@ -1,29 +1,159 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/config"
 	"github.com/antonio91capa/go-apirest/api/controllers"
-	"github.com/antonio91capa/go-apirest/api/dummy"
-	"github.com/joho/godotenv"
+	"github.com/antonio91capa/go-apirest/api/logging"
+	"github.com/antonio91capa/go-apirest/api/migrations"
+	"github.com/antonio91capa/go-apirest/api/tracing"
 )
 
 var server = controllers.Server{}
 
-func Run() {
-	var err error
-	err = godotenv.Load()
+const usage = `usage: go-apirest [options] [command]
+
+commands:
+  serve                      run the API (default)
+  migrate up|down|status     apply, roll back or list schema migrations
+  seed [-users N] [-posts N] [files...]
+                             upsert users and posts from YAML/JSON fixtures,
+                             fake data or the sample data`
+
+// Run executes the command given in args, serving the API by default, and
+// returns the exit status of the process once the command is done.
+func Run(args []string) int {
+	cfg, args, err := config.Load(args, usage)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return 1
+	}
+
+	logger, err := logging.New(cfg.Log, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring the logger: %v\n", err)
+		return 1
+	}
+	// The log package and the libraries using it write through the same handler
+	slog.SetDefault(logger)
+	server.Logger = logger
+
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		err = serve(cfg)
+	case "migrate":
+		err = migrate(cfg, args)
+	case "seed":
+		err = seed(cfg, args)
+	default:
+		err = usageError(fmt.Sprintf("unknown command %q\n%s", command, usage))
+	}
+	return exitStatus(err)
+}
+
+func serve(cfg *config.Config) error {
+	keys, err := auth.NewKeyManagerFromConfig(cfg.JWT)
+	if err != nil {
+		return failure("cannot load the signing keys", "error", err)
+	}
+	if cfg.JWT.RotationInterval > 0 {
+		keys.StartRotation(cfg.JWT.RotationInterval)
+		defer keys.Stop()
+	}
+	auth.SetKeyManager(keys)
+
+	// Spans go to stdout with the stdout exporter, the logs stay on stderr
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, os.Stdout)
 	if err != nil {
-		log.Fatalf("Error getting env: %v", err)
-	} else {
-		fmt.Println("Cargado los environments correctamente")
+		return failure("cannot set up tracing", "error", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("cannot flush the pending spans", "error", err)
+		}
+	}()
 
-	server.Initialize(os.Getenv("DB_DRIVER"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME"))
+	server.API = cfg.API
+	if err := server.Initialize(cfg.Database); err != nil {
+		return failure("cannot open the database", "error", err)
+	}
+
+	// The schema is only changed on boot when asked to, run "migrate up" otherwise
+	migrator := migrations.New(server.DB)
+	if cfg.Database.AutoMigrate {
+		if _, err := migrator.Up(); err != nil {
+			return failure("cannot migrate the database", "error", err)
+		}
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return failure("cannot read migration status", "error", err)
+	}
+	if len(pending) > 0 {
+		return failure("pending migrations, run \"migrate up\" before serving", "pending", len(pending))
+	}
+
+	if err := server.Run(cfg.HTTP); err != nil {
+		return failure("server stopped", "error", err)
+	}
+	slog.Info("server stopped")
+	return nil
+}
 
-	dummy.Load(server.DB)
+// commandError is a failed command, logged as msg with the attributes args.
+type commandError struct {
+	msg  string
+	args []any
+}
+
+func (e *commandError) Error() string {
+	return e.msg
+}
+
+func failure(msg string, args ...any) error {
+	return &commandError{msg: msg, args: args}
+}
 
-	server.Run(":8080")
+// usageError is a command line mistake, printed as is.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitStatus reports err, after the deferred calls of the command have run,
+// and returns the status to exit with, 2 for a usage error like the flag package.
+func exitStatus(err error) int {
+	var failed *commandError
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	case errors.As(err, &failed):
+		slog.Error(failed.msg, failed.args...)
+		return 1
+	}
+	slog.Error(err.Error())
+	return 1
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/exception"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+// PostService publishes posts for their authors. Authors edit and delete
+// their own posts, moderators and admins any post.
+type PostService struct {
+	posts repository.PostRepository
+	users repository.UserRepository
+	tx    repository.Transactor
+}
+
+// NewPostService loads the authors from users and runs the edits in tx, or
+// without a transaction when tx is nil.
+func NewPostService(posts repository.PostRepository, users repository.UserRepository, tx repository.Transactor) *PostService {
+	return &PostService{posts: posts, users: users, tx: tx}
+}
+
+// ****** Publish
+// Create publishes post as its author, the actor.
+func (s *PostService) Create(ctx context.Context, actor *auth.Principal, post models.Post, e models.PostExpansion) (*models.Post, error) {
+	post.Prepare()
+	if err := post.Validate(); err != nil {
+		return nil, err
+	}
+	if actor == nil {
+		return nil, exception.ErrUnauthorized
+	}
+	if actor.UserID != post.AuthorID {
+		return nil, exception.ErrForbidden
+	}
+
+	created, err := s.posts.Create(ctx, &post)
+	if err != nil {
+		return nil, err
+	}
+	if err = repository.ExpandPost(ctx, s.users, created, e); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ****** Read
+func (s *PostService) List(ctx context.Context, filter models.PostFilter, page models.Pagination, e models.PostExpansion) ([]models.Post, *models.PageInfo, error) {
+	posts, info, err := s.posts.FindAll(ctx, filter, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = repository.ExpandPosts(ctx, s.users, posts, e); err != nil {
+		return nil, nil, err
+	}
+	return posts, info, nil
+}
+
+func (s *PostService) Get(ctx context.Context, id uint64, e models.PostExpansion) (*models.Post, error) {
+	post, err := s.posts.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = repository.ExpandPost(ctx, s.users, post, e); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// ****** Edit
+// Update replaces the title and content of the post id. The author never
+// changes, even when a moderator edits the post.
+func (s *PostService) Update(ctx context.Context, actor *auth.Principal, id uint64, post models.Post, e models.PostExpansion) (*models.Post, error) {
+	var updated *models.Post
+	err := inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		current, err := s.editable(ctx, actor, id)
+		if err != nil {
+			return err
+		}
+
+		post.AuthorID = current.AuthorID
+		post.Prepare()
+		if err = post.Validate(); err != nil {
+			return err
+		}
+		post.ID = current.ID
+
+		updated, err = s.posts.Update(ctx, &post)
+		if err != nil {
+			return err
+		}
+		return repository.ExpandPost(ctx, s.users, updated, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *PostService) Delete(ctx context.Context, actor *auth.Principal, id uint64) error {
+	return inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if _, err := s.editable(ctx, actor, id); err != nil {
+			return err
+		}
+		return s.posts.Delete(ctx, id)
+	})
+}
+
+// editable loads the post id when actor may edit it.
+func (s *PostService) editable(ctx context.Context, actor *auth.Principal, id uint64) (*models.Post, error) {
+	if actor == nil {
+		return nil, exception.ErrUnauthorized
+	}
+	post, err := s.posts.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = middlewares.EditPost.Allow(actor, post.AuthorID == actor.UserID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+// inTransaction runs fn in a transaction of tx, or directly without one.
+func inTransaction(ctx context.Context, tx repository.Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.InTransaction(ctx, fn)
+}
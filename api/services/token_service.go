@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+	"github.com/antonio91capa/go-apirest/api/responses"
+)
+
+// TokenService issues the access and refresh tokens. Every refresh rotates the
+// refresh token, and presenting a revoked one revokes its whole family.
+type TokenService struct {
+	tokens repository.RefreshTokenRepository
+	users  repository.UserRepository
+	tx     repository.Transactor
+}
+
+// NewTokenService rotates the refresh tokens in tx, or without a transaction
+// when tx is nil.
+func NewTokenService(tokens repository.RefreshTokenRepository, users repository.UserRepository, tx repository.Transactor) *TokenService {
+	return &TokenService{tokens: tokens, users: users, tx: tx}
+}
+
+// ****** Login
+// Issue starts a new family of refresh tokens for user, signed in.
+func (s *TokenService) Issue(ctx context.Context, user *models.User) (*responses.Tokens, error) {
+	familyID, err := auth.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, familyID)
+}
+
+// ****** Refresh
+// Refresh exchanges refreshToken for new tokens with the current roles of its user.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*responses.Tokens, error) {
+	current, err := s.tokens.FindByHash(ctx, auth.HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.FindByID(ctx, current.UserID)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, models.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is consumed and replaced in one transaction
+	var tokens *responses.Tokens
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.tokens.Consume(ctx, current); err != nil {
+			return err
+		}
+		tokens, err = s.issue(ctx, user, current.FamilyID)
+		return err
+	})
+	// A replayed token, or the loser of concurrent refreshes of the same
+	// token, may have been stolen: its whole family is revoked
+	if errors.Is(err, models.ErrRefreshTokenReused) {
+		if err := s.tokens.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// ****** Logout
+// Logout revokes the access token of principal and the family of
+// refreshToken, or every refresh token of the user when it is empty. An
+// unknown refresh token, or one of another user, is ignored.
+func (s *TokenService) Logout(ctx context.Context, principal *auth.Principal, refreshToken string) error {
+	if refreshToken != "" {
+		current, err := s.tokens.FindByHash(ctx, auth.HashRefreshToken(refreshToken))
+		if err == nil && current.UserID == principal.UserID {
+			err = s.tokens.RevokeFamily(ctx, current.FamilyID)
+		}
+		if err != nil && !errors.Is(err, models.ErrRefreshTokenInvalid) {
+			return err
+		}
+	} else if err := s.tokens.RevokeUser(ctx, principal.UserID); err != nil {
+		return err
+	}
+	return auth.Revoke(principal)
+}
+
+// issue creates an access token with the current roles of user and a new
+// refresh token in the family familyID
+func (s *TokenService) issue(ctx context.Context, user *models.User, familyID string) (*responses.Tokens, error) {
+	accessToken, err := auth.CreateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.tokens.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &responses.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(auth.AccessTokenTTL / time.Second),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/config"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+	"github.com/antonio91capa/go-apirest/api/services"
+)
+
+// staleTokens finds the refresh tokens as they were before being consumed,
+// like a refresh reading the token right before a concurrent one commits.
+type staleTokens struct {
+	repository.RefreshTokenRepository
+}
+
+func (r staleTokens) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token, err := r.RefreshTokenRepository.FindByHash(ctx, hash)
+	token.RevokedAt = nil
+	return token, err
+}
+
+func TestRefreshRaceRevokesFamily(t *testing.T) {
+	keys, err := auth.NewKeyManagerFromConfig(config.JWT{Algorithm: "HS256", Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.SetKeyManager(keys)
+
+	ctx := context.Background()
+	memory := repository.NewMemory()
+	user, err := memory.Users().Create(ctx, &models.User{Nickname: "alice", Email: "alice@mail.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := services.NewTokenService(staleTokens{memory.RefreshTokens()}, memory.Users(), memory)
+	first, err := tokens.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	winner, err := tokens.Refresh(ctx, first.RefreshToken)
+	assert.Equal(t, err, nil)
+	// The loser saw the token unrevoked, its refresh is a reuse all the same
+	_, err = tokens.Refresh(ctx, first.RefreshToken)
+	assert.Equal(t, errors.Is(err, models.ErrRefreshTokenReused), true)
+
+	// The token of the winner went with the family
+	_, err = tokens.Refresh(ctx, winner.RefreshToken)
+	assert.Equal(t, errors.Is(err, models.ErrRefreshTokenReused), true)
+}
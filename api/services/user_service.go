@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/antonio91capa/go-apirest/api/auth"
+	"github.com/antonio91capa/go-apirest/api/middlewares"
+	"github.com/antonio91capa/go-apirest/api/models"
+	"github.com/antonio91capa/go-apirest/api/repository"
+)
+
+// UserService signs users up and lets them, or the admins, manage their
+// accounts. Only admins grant roles.
+type UserService struct {
+	users repository.UserRepository
+	posts repository.PostRepository
+	tx    repository.Transactor
+}
+
+// NewUserService deletes the posts of the users deleted, and runs the changes
+// in tx, or without a transaction when tx is nil.
+func NewUserService(users repository.UserRepository, posts repository.PostRepository, tx repository.Transactor) *UserService {
+	return &UserService{users: users, posts: posts, tx: tx}
+}
+
+// ****** Sign up
+func (s *UserService) Register(ctx context.Context, user models.User) (*models.User, error) {
+	user.Prepare()
+	if err := user.ValidateCreate(); err != nil {
+		return nil, err
+	}
+	hashed, err := models.Hash(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(hashed)
+	return s.users.Create(ctx, &user)
+}
+
+// Authenticate returns the user with email and password, failing with
+// models.ErrInvalidCredentials whether the email or the password is wrong.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	credentials := models.User{Email: email, Password: password}
+	credentials.Prepare()
+	if err := credentials.ValidateLogin(); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.FindByEmail(ctx, credentials.Email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = models.VerifyPassword(user.Password, password); err != nil {
+		return nil, models.ErrInvalidCredentials.Wrap(err)
+	}
+	return user, nil
+}
+
+// ****** Read
+func (s *UserService) List(ctx context.Context, page models.Pagination) ([]models.User, *models.PageInfo, error) {
+	return s.users.FindAll(ctx, page)
+}
+
+func (s *UserService) Get(ctx context.Context, id uint32) (*models.User, error) {
+	return s.users.FindByID(ctx, id)
+}
+
+// ****** Manage
+// Update replaces the profile and password of the user id.
+func (s *UserService) Update(ctx context.Context, actor *auth.Principal, id uint32, user models.User) (*models.User, error) {
+	if err := middlewares.ManageUser.Allow(actor, actor != nil && actor.UserID == id); err != nil {
+		return nil, err
+	}
+	user.Prepare()
+	if err := user.ValidateUpdate(); err != nil {
+		return nil, err
+	}
+	hashed, err := models.Hash(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(hashed)
+
+	var updated *models.User
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.users.Update(ctx, id, &user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *UserService) UpdateRole(ctx context.Context, actor *auth.Principal, id uint32, role string) (*models.User, error) {
+	if err := middlewares.GrantRole.Allow(actor, false); err != nil {
+		return nil, err
+	}
+	v := models.Validator{}
+	v.Check(models.ValidRole(role), "role", "oneof", "role must be one of user, moderator, admin")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	var updated *models.User
+	err := inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.users.UpdateRole(ctx, id, role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete removes the user id with its posts.
+func (s *UserService) Delete(ctx context.Context, actor *auth.Principal, id uint32) error {
+	if err := middlewares.ManageUser.Allow(actor, actor != nil && actor.UserID == id); err != nil {
+		return err
+	}
+	return inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if _, err := s.users.FindByID(ctx, id); err != nil {
+			return err
+		}
+		if err := s.posts.DeleteByAuthor(ctx, id); err != nil {
+			return err
+		}
+		return s.users.Delete(ctx, id)
+	})
+}
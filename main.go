@@ -1,7 +1,11 @@
 package main
 
-import "github.com/antonio91capa/go-apirest/api"
+import (
+	"os"
+
+	"github.com/antonio91capa/go-apirest/api"
+)
 
 func main() {
-	api.Run()
+	os.Exit(api.Run(os.Args[1:]))
 }